@@ -0,0 +1,307 @@
+/*****************************************************************************
+*
+*	File			: auth.go
+*
+* 	Created			: 19 Oct 2026
+*
+*	Description		: API authentication on top of mTLS, for environments behind a gateway. Applied by httpCALL to every
+*					: request (incl. retries).
+*
+*					: authType
+*					:	"" / none	=> mTLS only
+*					:	bearer		=> Authorization: Bearer <authToken>
+*					:	apikey		=> <authHeader, default X-API-Key>: <authKey>
+*					:	hmac		=> X-Timestamp: <unix seconds>
+*					:				   <authHeader, default X-Signature>: keyId=<authKey>,algorithm=hmac-sha256,signature=<hex>
+*					:				   signature = HMAC-SHA256(authSecret, "<timestamp>.<body as sent>")
+*					:	oauth2		=> OAuth2 client credentials grant against oauthTokenURL (oauthClientId/oauthClientSecret
+*					:				   as basic auth, oauthScope), the token is cached and refreshed 30s (half the lifetime
+*					:				   for tokens of a minute or less) before it expires, or when the API responds with a 401.
+*
+*					: fs_producer tokenstub <env> [address] [expires_in seconds]
+*					: runs a local client credentials token endpoint (POST /token) for testing, it only issues tokens
+*					: for the env's oauthClientId/oauthClientSecret.
+*
+*	By				: George Leonard (georgelza@gmail.com)
+*
+*****************************************************************************/
+
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Adds the credentials to a request, body is the request body as sent (after gzip).
+type AuthProvider interface {
+	Apply(Request *http.Request, body []byte) error
+}
+
+// Providers holding a token that can go stale, called when the API responds with a 401.
+type tokenRefresher interface {
+	Invalidate()
+}
+
+var vAuth AuthProvider
+
+func newAuthProvider(client *http.Client) (AuthProvider, error) {
+
+	switch vGeneral.AuthType {
+	case "", "none":
+		return nil, nil
+
+	case "bearer":
+		if vGeneral.AuthToken == "" {
+			return nil, errors.New("authType bearer requires authToken")
+		}
+		return &bearerAuth{token: vGeneral.AuthToken}, nil
+
+	case "apikey":
+		if vGeneral.AuthKey == "" {
+			return nil, errors.New("authType apikey requires authKey")
+		}
+		return &apiKeyAuth{header: headerOrDefault(vGeneral.AuthHeader, "X-API-Key"), key: vGeneral.AuthKey}, nil
+
+	case "hmac":
+		if vGeneral.AuthSecret == "" {
+			return nil, errors.New("authType hmac requires authSecret")
+		}
+		return &hmacAuth{header: headerOrDefault(vGeneral.AuthHeader, "X-Signature"), keyId: vGeneral.AuthKey, secret: []byte(vGeneral.AuthSecret)}, nil
+
+	case "oauth2":
+		if vGeneral.OAuthTokenURL == "" || vGeneral.OAuthClientId == "" {
+			return nil, errors.New("authType oauth2 requires oauthTokenURL and oauthClientId")
+		}
+		return &oauth2Auth{client: client}, nil
+
+	}
+
+	return nil, fmt.Errorf("unknown authType %s, use none, bearer, apikey, hmac or oauth2", vGeneral.AuthType)
+}
+
+func headerOrDefault(header string, def string) string {
+
+	if header == "" {
+		return def
+	}
+
+	return header
+}
+
+type bearerAuth struct {
+	token string
+}
+
+func (a *bearerAuth) Apply(Request *http.Request, body []byte) error {
+
+	Request.Header.Set("Authorization", "Bearer "+a.token)
+
+	return nil
+}
+
+type apiKeyAuth struct {
+	header string
+	key    string
+}
+
+func (a *apiKeyAuth) Apply(Request *http.Request, body []byte) error {
+
+	Request.Header.Set(a.header, a.key)
+
+	return nil
+}
+
+type hmacAuth struct {
+	header string
+	keyId  string
+	secret []byte
+}
+
+func (a *hmacAuth) Apply(Request *http.Request, body []byte) error {
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	mac := hmac.New(sha256.New, a.secret)
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+
+	Request.Header.Set("X-Timestamp", timestamp)
+	Request.Header.Set(a.header, fmt.Sprintf("keyId=%s,algorithm=hmac-sha256,signature=%s", a.keyId, hex.EncodeToString(mac.Sum(nil))))
+
+	return nil
+}
+
+// Client credentials grant, the token is shared by all workers.
+type oauth2Auth struct {
+	client *http.Client
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+	refresh time.Time // ahead of expires, so in-flight requests don't carry a token that just expired
+}
+
+type oauth2Token struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+func (a *oauth2Auth) Apply(Request *http.Request, body []byte) error {
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.token == "" || time.Now().After(a.refresh) {
+		if err := a.fetch(); err != nil {
+			return err
+		}
+	}
+	Request.Header.Set("Authorization", "Bearer "+a.token)
+
+	return nil
+}
+
+func (a *oauth2Auth) Invalidate() {
+
+	a.mu.Lock()
+	a.token = ""
+	a.mu.Unlock()
+}
+
+func (a *oauth2Auth) fetch() error {
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if vGeneral.OAuthScope != "" {
+		form.Set("scope", vGeneral.OAuthScope)
+	}
+
+	Request, err := http.NewRequest("POST", vGeneral.OAuthTokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("oauth2 token request error: %s", err)
+	}
+	Request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	Request.SetBasicAuth(url.QueryEscape(vGeneral.OAuthClientId), url.QueryEscape(vGeneral.OAuthClientSecret))
+
+	Response, err := a.client.Do(Request)
+	if err != nil {
+		return fmt.Errorf("oauth2 token request error: %s", err)
+	}
+	defer Response.Body.Close()
+
+	responseBody, _ := io.ReadAll(Response.Body)
+	if Response.StatusCode != http.StatusOK {
+		return fmt.Errorf("oauth2 token request failed: %s %s", Response.Status, string(responseBody))
+	}
+
+	var token oauth2Token
+	if err = json.Unmarshal(responseBody, &token); err != nil || token.AccessToken == "" {
+		return fmt.Errorf("oauth2 token response invalid: %s", string(responseBody))
+	}
+
+	// No expires_in, assume a hour
+	if token.ExpiresIn <= 0 {
+		token.ExpiresIn = 3600
+	}
+
+	// A fixed 30s margin would leave short lived tokens stale on arrival, fetched for every request
+	lifetime := time.Duration(token.ExpiresIn) * time.Second
+	margin := 30 * time.Second
+	if margin > lifetime/2 {
+		margin = lifetime / 2
+	}
+
+	a.token = token.AccessToken
+	a.expires = time.Now().Add(lifetime)
+	a.refresh = a.expires.Add(-margin)
+
+	if vGeneral.Debuglevel > 1 {
+		grpcLog.Infoln("OAuth2 token fetched, expires :", a.expires)
+
+	}
+
+	return nil
+}
+
+// fs_producer tokenstub <env> [address] [expires_in seconds]
+func runTokenStub(args []string) {
+
+	if len(args) < 1 {
+		grpcLog.Fatalln("Usage: fs_producer tokenstub <env> [address] [expires_in seconds]")
+
+	}
+
+	vGeneral = loadConfig(args[0])
+
+	address := "127.0.0.1:18090"
+	if len(args) > 1 {
+		address = args[1]
+	}
+
+	expiresIn := 300
+	if len(args) > 2 {
+		n, err := strconv.Atoi(args[2])
+		if err != nil {
+			grpcLog.Fatalln("Invalid expires_in: ", args[2])
+
+		}
+		expiresIn = n
+	}
+
+	grpcLog.Infoln("Token endpoint listening on   :", "http://"+address+"/token")
+	if err := http.ListenAndServe(address, tokenStubHandler(vGeneral.OAuthClientId, vGeneral.OAuthClientSecret, expiresIn)); err != nil {
+		grpcLog.Fatalln("Token endpoint error: ", err)
+
+	}
+}
+
+// POST /token, issues tokens for the one client only.
+func tokenStubHandler(oauthClientId string, oauthClientSecret string, expiresIn int) http.Handler {
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+
+		clientId, clientSecret, ok := r.BasicAuth()
+		if ok {
+			clientId, _ = url.QueryUnescape(clientId)
+			clientSecret, _ = url.QueryUnescape(clientSecret)
+		} else {
+			clientId, clientSecret = r.FormValue("client_id"), r.FormValue("client_secret")
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.Method != "POST" || r.FormValue("grant_type") != "client_credentials" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "unsupported_grant_type"})
+			return
+		}
+
+		if clientId != oauthClientId || clientSecret != oauthClientSecret {
+			grpcLog.Infoln("Token refused                 :", clientId)
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+			return
+		}
+
+		token := oauth2Token{AccessToken: uuid.New().String(), TokenType: "Bearer", ExpiresIn: expiresIn}
+		grpcLog.Infoln("Token issued                  :", clientId, token.AccessToken)
+		json.NewEncoder(w).Encode(token)
+	})
+
+	return mux
+}
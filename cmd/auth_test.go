@@ -0,0 +1,204 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"cmd/types"
+)
+
+func TestAuthProviders(t *testing.T) {
+
+	body := []byte(`{"eventId":"1"}`)
+
+	tests := []struct {
+		name    string
+		general types.Tp_general
+		wantErr bool
+		check   func(t *testing.T, header http.Header)
+	}{
+		{
+			name:    "none",
+			general: types.Tp_general{AuthType: "none"},
+			check: func(t *testing.T, header http.Header) {
+				if len(header) != 0 {
+					t.Errorf("headers set: %v", header)
+				}
+			},
+		},
+		{
+			name:    "bearer",
+			general: types.Tp_general{AuthType: "bearer", AuthToken: "tok"},
+			check: func(t *testing.T, header http.Header) {
+				if got := header.Get("Authorization"); got != "Bearer tok" {
+					t.Errorf("Authorization = %q", got)
+				}
+			},
+		},
+		{
+			name:    "bearer without token",
+			general: types.Tp_general{AuthType: "bearer"},
+			wantErr: true,
+		},
+		{
+			name:    "apikey default header",
+			general: types.Tp_general{AuthType: "apikey", AuthKey: "key1"},
+			check: func(t *testing.T, header http.Header) {
+				if got := header.Get("X-API-Key"); got != "key1" {
+					t.Errorf("X-API-Key = %q", got)
+				}
+			},
+		},
+		{
+			name:    "apikey custom header",
+			general: types.Tp_general{AuthType: "apikey", AuthKey: "key1", AuthHeader: "X-Gateway-Key"},
+			check: func(t *testing.T, header http.Header) {
+				if got := header.Get("X-Gateway-Key"); got != "key1" {
+					t.Errorf("X-Gateway-Key = %q", got)
+				}
+			},
+		},
+		{
+			name:    "hmac",
+			general: types.Tp_general{AuthType: "hmac", AuthKey: "k1", AuthSecret: "s3cret"},
+			check: func(t *testing.T, header http.Header) {
+				timestamp := header.Get("X-Timestamp")
+				mac := hmac.New(sha256.New, []byte("s3cret"))
+				mac.Write([]byte(timestamp + "."))
+				mac.Write(body)
+
+				want := fmt.Sprintf("keyId=k1,algorithm=hmac-sha256,signature=%s", hex.EncodeToString(mac.Sum(nil)))
+				if got := header.Get("X-Signature"); timestamp == "" || got != want {
+					t.Errorf("X-Signature = %q, want %q", got, want)
+				}
+			},
+		},
+		{
+			name:    "hmac without secret",
+			general: types.Tp_general{AuthType: "hmac", AuthKey: "k1"},
+			wantErr: true,
+		},
+		{
+			name:    "oauth2 without token url",
+			general: types.Tp_general{AuthType: "oauth2", OAuthClientId: "c1"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown",
+			general: types.Tp_general{AuthType: "kerberos"},
+			wantErr: true,
+		},
+	}
+
+	defer func(saved types.Tp_general) { vGeneral = saved }(vGeneral)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vGeneral = tt.general
+
+			auth, err := newAuthProvider(http.DefaultClient)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newAuthProvider() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			Request := httptest.NewRequest("POST", "/events", nil)
+			if auth != nil {
+				if err = auth.Apply(Request, body); err != nil {
+					t.Fatalf("Apply() error = %v", err)
+				}
+			}
+			tt.check(t, Request.Header)
+		})
+	}
+}
+
+// The oauth2 provider against the tokenstub endpoint.
+func TestOAuth2TokenStub(t *testing.T) {
+
+	tests := []struct {
+		name       string
+		secret     string
+		scope      string
+		expiresIn  int
+		applies    int
+		expire     bool // refresh time passed before each further apply
+		invalidate bool
+		wantErr    bool
+		wantFetch  int64
+	}{
+		{name: "token cached", secret: "cs1", expiresIn: 300, applies: 3, wantFetch: 1},
+		{name: "with scope", secret: "cs1", scope: "events:write", expiresIn: 300, applies: 1, wantFetch: 1},
+		{name: "short lived token cached", secret: "cs1", expiresIn: 20, applies: 3, wantFetch: 1},
+		{name: "one second token cached", secret: "cs1", expiresIn: 1, applies: 3, wantFetch: 1},
+		{name: "expiring token refetched", secret: "cs1", expiresIn: 300, applies: 3, expire: true, wantFetch: 3},
+		{name: "invalidated token refetched", secret: "cs1", expiresIn: 300, applies: 2, invalidate: true, wantFetch: 2},
+		{name: "invalid client", secret: "wrong", expiresIn: 300, applies: 1, wantErr: true, wantFetch: 1},
+	}
+
+	defer func(saved types.Tp_general) { vGeneral = saved }(vGeneral)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fetches int64
+			stub := tokenStubHandler("client:1", "cs1", tt.expiresIn)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt64(&fetches, 1)
+				stub.ServeHTTP(w, r)
+			}))
+			defer server.Close()
+
+			vGeneral = types.Tp_general{AuthType: "oauth2", OAuthTokenURL: server.URL + "/token", OAuthClientId: "client:1", OAuthClientSecret: tt.secret, OAuthScope: tt.scope}
+
+			auth, err := newAuthProvider(server.Client())
+			if err != nil {
+				t.Fatalf("newAuthProvider() error = %v", err)
+			}
+
+			var tokens []string
+			for i := 0; i < tt.applies; i++ {
+				if tt.invalidate && i > 0 {
+					auth.(tokenRefresher).Invalidate()
+				}
+				if tt.expire && i > 0 {
+					auth.(*oauth2Auth).refresh = time.Now().Add(-time.Second)
+				}
+
+				Request := httptest.NewRequest("POST", "/events", nil)
+				err = auth.Apply(Request, nil)
+				if tt.wantErr {
+					if err == nil {
+						t.Fatal("Apply() succeeded, want error")
+					}
+					break
+				}
+				if err != nil {
+					t.Fatalf("Apply() error = %v", err)
+				}
+
+				authorization := Request.Header.Get("Authorization")
+				if !strings.HasPrefix(authorization, "Bearer ") {
+					t.Fatalf("Authorization = %q", authorization)
+				}
+				tokens = append(tokens, authorization)
+			}
+
+			if got := atomic.LoadInt64(&fetches); got != tt.wantFetch {
+				t.Errorf("token fetches = %d, want %d", got, tt.wantFetch)
+			}
+			if tt.wantFetch == 1 && len(tokens) > 1 && tokens[0] != tokens[len(tokens)-1] {
+				t.Errorf("cached token changed, %s => %s", tokens[0], tokens[len(tokens)-1])
+			}
+		})
+	}
+}
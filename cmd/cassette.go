@@ -0,0 +1,265 @@
+/*****************************************************************************
+*
+*	File			: cassette.go
+*
+* 	Created			: 19 Oct 2026
+*
+*	Description		: HTTP record/replay cassettes, so scoring extraction, reporting and assertions can be rerun against
+*					: real engine output without network access.
+*
+*					: cassette
+*					:	record	=> every request and response (status, headers, body) posted via the sink is appended
+*					:			   to cassette_file, one JSON line per interaction
+*					:	replay	=> nothing is posted, the responses are served from cassette_file
+*
+*					: Interactions are matched on the scenario step and the event, not the eventId/transactionId which
+*					: change every run. The step is the scenario (input) file name when json_from_file = 1, else the
+*					: record number, the event is eventType + direction. A step/event recorded more than once (soak
+*					: runs cycling through the inputs) is replayed in recorded order, wrapping around.
+*
+*	By				: George Leonard (georgelza@gmail.com)
+*
+*****************************************************************************/
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type cassetteEntry struct {
+	Step               string          `json:"step"`
+	EventType          string          `json:"eventType"`
+	Direction          string          `json:"direction,omitempty"`
+	EventId            interface{}     `json:"eventId"`
+	Request            json.RawMessage `json:"request"`
+	ResponseStatus     string          `json:"responseStatus"`
+	ResponseStatusCode int             `json:"responseStatusCode"`
+	ResponseHeaders    http.Header     `json:"responseHeaders,omitempty"`
+	ResponseBody       string          `json:"responseBody,omitempty"`
+	RecordedAt         time.Time       `json:"recordedAt"`
+}
+
+// transactionId => scenario step, set by processTransaction for the life of the transaction
+var vScenarioSteps sync.Map
+
+func setScenarioStep(transactionId interface{}, step string) {
+	vScenarioSteps.Store(fmt.Sprintf("%v", transactionId), step)
+}
+
+func clearScenarioStep(transactionId interface{}) {
+	vScenarioSteps.Delete(fmt.Sprintf("%v", transactionId))
+}
+
+// The match key of a event, events outside a scenario step (repost) fall back to their transactionId.
+func cassetteKey(t_Payload map[string]interface{}) (key string, step string) {
+
+	transactionId := fmt.Sprintf("%v", t_Payload["transactionId"])
+
+	step = transactionId
+	if value, ok := vScenarioSteps.Load(transactionId); ok {
+		step = value.(string)
+	}
+
+	return fmt.Sprintf("%s|%v|%s", step, t_Payload["eventType"], payloadDirection(t_Payload)), step
+}
+
+func payloadDirection(t_Payload map[string]interface{}) string {
+
+	if direction, ok := t_Payload["direction"]; ok && direction != nil {
+		return fmt.Sprintf("%v", direction)
+	}
+
+	return ""
+}
+
+func newCassetteSink(base Sink) (Sink, error) {
+
+	if vGeneral.Cassette_file == "" {
+		return nil, fmt.Errorf("cassette %s requires cassette_file", vGeneral.Cassette)
+	}
+
+	switch vGeneral.Cassette {
+	case "record":
+		fd, err := os.OpenFile(vGeneral.Cassette_file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("cassette_file open error %s: %s", vGeneral.Cassette_file, err)
+		}
+		return &cassetteRecorder{base: base, w: bufio.NewWriter(fd), file: fd}, nil
+
+	case "replay":
+		return loadCassette()
+
+	}
+
+	return nil, fmt.Errorf("unknown cassette %s, use record or replay", vGeneral.Cassette)
+}
+
+// Posts via the base sink and records the interaction.
+type cassetteRecorder struct {
+	base Sink
+
+	mu   sync.Mutex
+	w    *bufio.Writer
+	file *os.File
+}
+
+func (c *cassetteRecorder) Send(t_Payload map[string]interface{}, Bytes []byte) (*http.Response, int, error) {
+
+	Response, retries, err := c.base.Send(t_Payload, Bytes)
+	if err != nil {
+		// Nothing to replay
+		return Response, retries, err
+	}
+
+	// Read the body here, and hand the caller a copy
+	responseBody, err := io.ReadAll(Response.Body)
+	Response.Body.Close()
+	if err != nil {
+		return Response, retries, fmt.Errorf("cassette response read error: %s", err)
+	}
+	Response.Body = io.NopCloser(bytes.NewReader(responseBody))
+
+	_, step := cassetteKey(t_Payload)
+	entry := cassetteEntry{
+		Step:               step,
+		EventType:          fmt.Sprintf("%v", t_Payload["eventType"]),
+		Direction:          payloadDirection(t_Payload),
+		EventId:            t_Payload["eventId"],
+		Request:            Bytes,
+		ResponseStatus:     Response.Status,
+		ResponseStatusCode: Response.StatusCode,
+		ResponseHeaders:    Response.Header,
+		ResponseBody:       string(responseBody),
+		RecordedAt:         time.Now(),
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		grpcLog.Errorln("Cassette marshal error", err)
+		return Response, retries, nil
+
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.w.Write(line)
+	c.w.WriteByte('\n')
+	if err = c.w.Flush(); err != nil {
+		grpcLog.Errorln("Cassette write error", err)
+
+	}
+
+	return Response, retries, nil
+}
+
+func (c *cassetteRecorder) Close() error {
+
+	c.mu.Lock()
+	c.w.Flush()
+	err := c.file.Close()
+	c.mu.Unlock()
+
+	if cerr := c.base.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+// Serves the recorded responses.
+type cassettePlayer struct {
+	mu      sync.Mutex
+	entries map[string][]cassetteEntry
+	next    map[string]int
+	misses  int
+}
+
+func loadCassette() (Sink, error) {
+
+	fd, err := os.Open(vGeneral.Cassette_file)
+	if err != nil {
+		return nil, fmt.Errorf("cassette_file open error %s: %s", vGeneral.Cassette_file, err)
+	}
+	defer fd.Close()
+
+	c := &cassettePlayer{entries: make(map[string][]cassetteEntry), next: make(map[string]int)}
+
+	var count int
+	scanner := bufio.NewScanner(fd)
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		var entry cassetteEntry
+		if err = json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("cassette_file %s line %d: %s", vGeneral.Cassette_file, count+1, err)
+		}
+
+		key := fmt.Sprintf("%s|%s|%s", entry.Step, entry.EventType, entry.Direction)
+		c.entries[key] = append(c.entries[key], entry)
+		count++
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cassette_file read error %s: %s", vGeneral.Cassette_file, err)
+	}
+
+	grpcLog.Infoln("Cassette interactions loaded  :", count)
+
+	return c, nil
+}
+
+func (c *cassettePlayer) Send(t_Payload map[string]interface{}, Bytes []byte) (*http.Response, int, error) {
+
+	key, _ := cassetteKey(t_Payload)
+
+	c.mu.Lock()
+	entries := c.entries[key]
+	if len(entries) == 0 {
+		c.misses++
+		c.mu.Unlock()
+
+		err := fmt.Errorf("no cassette interaction for %s", key)
+		grpcLog.Errorln(err)
+		return nil, 0, err
+
+	}
+	entry := entries[c.next[key]%len(entries)]
+	c.next[key]++
+	c.mu.Unlock()
+
+	Response := sinkResponse(entry.ResponseStatusCode)
+	Response.Status = entry.ResponseStatus
+	if entry.ResponseHeaders != nil {
+		Response.Header = entry.ResponseHeaders.Clone()
+	}
+	Response.Body = io.NopCloser(bytes.NewReader([]byte(entry.ResponseBody)))
+	atomic.AddInt64(&vStats.events, 1)
+
+	return Response, 0, nil
+}
+
+func (c *cassettePlayer) Close() error {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.misses > 0 {
+		grpcLog.Warningln("Cassette misses               :", c.misses)
+
+	}
+
+	return nil
+}
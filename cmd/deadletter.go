@@ -0,0 +1,277 @@
+/*****************************************************************************
+*
+*	File			: deadletter.go
+*
+* 	Created			: 19 Oct 2026
+*
+*	Description		: Dead-letter handling. Every event that fails (no response after retries, a non 200/204 response or a
+*					: response read error) is written to deadLetter_path together with the request body, response status,
+*					: headers and body. Events of the same transaction that were not posted because of the failure are
+*					: written as well, so that the transaction can be re-posted in order, once a event is dead-lettered
+*					: (also for a non 200/204 response) the transaction's later events are not posted.
+*
+*					: continueOnError = 1, the run carries on with the next transaction, else the run is stopped as for a
+*					: Ctrl-C, the in-flight transactions complete and the run's output is still written (see shutdown.go).
+*
+*					: fs_producer repost <env> [directory]
+*					: re-posts the dead-letter directory (default deadLetter_path), per transaction in the original order.
+*					: The original request body is re-posted, so the eventId is unchanged. Successfully re-posted events
+*					: are moved into <directory>/reposted.
+*
+*					: Files are named <transactionId>-<failed at, unix nano>-<eventId>.json, sorting them by name
+*					: groups the events per transaction in posting order. Characters other than letters, digits, '-',
+*					: '_' and '.' are replaced by '_', a missing transactionId/eventId is named "unknown".
+*
+*	By				: George Leonard (georgelza@gmail.com)
+*
+*****************************************************************************/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode"
+)
+
+var errNotPosted = errors.New("not posted, an earlier event in the transaction failed")
+
+type deadLetter struct {
+	TransactionId      interface{}     `json:"transactionId"`
+	EventId            interface{}     `json:"eventId"`
+	EventType          interface{}     `json:"eventType"`
+	Request            json.RawMessage `json:"request"`
+	ResponseStatus     string          `json:"responseStatus,omitempty"`
+	ResponseStatusCode int             `json:"responseStatusCode,omitempty"`
+	ResponseHeaders    http.Header     `json:"responseHeaders,omitempty"`
+	ResponseBody       string          `json:"responseBody,omitempty"`
+	Error              string          `json:"error,omitempty"`
+	Retries            int             `json:"retries"`
+	FailedAt           time.Time       `json:"failedAt"`
+}
+
+// Write a failed event to the dead-letter directory, Response/responseBody are nil if we never got a response.
+func writeDeadLetter(t_Payload map[string]interface{}, Response *http.Response, responseBody []byte, retries int, err error) {
+
+	atomic.AddInt64(&vStats.failed, 1)
+
+	request, _ := json.Marshal(t_Payload)
+
+	record := deadLetter{
+		TransactionId: t_Payload["transactionId"],
+		EventId:       t_Payload["eventId"],
+		EventType:     t_Payload["eventType"],
+		Request:       request,
+		ResponseBody:  string(responseBody),
+		Retries:       retries,
+		FailedAt:      time.Now().UTC(),
+	}
+	if Response != nil {
+		record.ResponseStatus = Response.Status
+		record.ResponseStatusCode = Response.StatusCode
+		record.ResponseHeaders = Response.Header
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+
+	recordFailure(record)
+
+	if vGeneral.DeadLetter_path == "" {
+		return
+	}
+
+	loc := fmt.Sprintf("%s%s%s-%d-%s.json", vGeneral.DeadLetter_path, pathSep, deadLetterNamePart(record.TransactionId), record.FailedAt.UnixNano(), deadLetterNamePart(record.EventId))
+	if vGeneral.Debuglevel > 1 {
+		grpcLog.Infoln("Dead-letter file              :", loc)
+
+	}
+
+	fd, err := json.MarshalIndent(record, "", " ")
+	if err != nil {
+		grpcLog.Errorln("MarshalIndent error", err)
+
+	}
+
+	err = os.WriteFile(loc, fd, 0644)
+	if err != nil {
+		grpcLog.Errorln("os.WriteFile error", err)
+
+	}
+}
+
+// A transactionId/eventId as part of a file name, safe on Windows as well.
+func deadLetterNamePart(value interface{}) string {
+
+	if value == nil || fmt.Sprintf("%v", value) == "" {
+		return "unknown"
+	}
+
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' || r == '.' {
+			return r
+		}
+		return '_'
+	}, fmt.Sprintf("%v", value))
+}
+
+// Was the event accepted by the sink, anything else is dead-lettered.
+func eventAccepted(Response *http.Response) bool {
+	return Response != nil && (Response.StatusCode == http.StatusOK || Response.StatusCode == http.StatusNoContent)
+}
+
+// A event could not be posted, dead-letter it and the remaining (unposted) events of the transaction. The caller
+// abandons the transaction and returns the error, without continueOnError the run is then stopped (see runLoader).
+func abortTransaction(err error, retries int, t_Failed map[string]interface{}, t_Unposted ...map[string]interface{}) {
+
+	writeDeadLetter(t_Failed, nil, nil, retries, err)
+	writeResultLine(t_Failed, 0, nil, err)
+	skipUnposted(t_Unposted...)
+
+	grpcLog.Errorln("Transaction abandoned         :", t_Failed["transactionId"], err)
+
+}
+
+// A earlier event of the transaction was dead-lettered, the remaining events are dead-lettered without posting them,
+// a repost then replays the transaction in order.
+func skipUnposted(t_Unposted ...map[string]interface{}) {
+
+	for _, t_Payload := range t_Unposted {
+		writeDeadLetter(t_Payload, nil, nil, 0, errNotPosted)
+		writeResultLine(t_Payload, 0, nil, errNotPosted)
+
+		if vGeneral.Debuglevel > 1 {
+			grpcLog.Infoln("Event not posted              :", t_Payload["eventType"], t_Payload["eventId"])
+
+		}
+	}
+}
+
+// fs_producer repost <env> [directory]
+func runRepost(args []string) {
+
+	if len(args) < 1 {
+		grpcLog.Fatalln("Usage: fs_producer repost <env> [directory]")
+
+	}
+
+	vGeneral = loadConfig(args[0])
+
+	directory := vGeneral.DeadLetter_path
+	if len(args) > 1 {
+		directory = args[1]
+	}
+
+	client, err := ConstructHTTPClient()
+	if err != nil {
+		os.Exit(1)
+
+	}
+
+	vSink, err = newSink(client)
+	if err != nil {
+		grpcLog.Fatalln("Sink error: ", err)
+
+	}
+
+	files, err := os.ReadDir(directory)
+	if err != nil {
+		grpcLog.Fatalln("Problem retrieving list of dead-letter files: ", err)
+
+	}
+
+	var names []string
+	for _, file := range files {
+		if !file.IsDir() && strings.HasSuffix(file.Name(), ".json") {
+			names = append(names, file.Name())
+		}
+	}
+	sort.Strings(names)
+
+	repostedDir := fmt.Sprintf("%s%sreposted", directory, pathSep)
+	if err = os.MkdirAll(repostedDir, 0755); err != nil {
+		grpcLog.Fatalln("Problem creating reposted directory: ", err)
+
+	}
+
+	var reposted, failed, skipped int
+	var failedTxn interface{}
+
+	for _, name := range names {
+
+		filename := fmt.Sprintf("%s%s%s", directory, pathSep, name)
+
+		content, err := os.ReadFile(filename)
+		if err != nil {
+			grpcLog.Errorln("ReadFile error", err)
+			failed++
+			continue
+
+		}
+
+		var record deadLetter
+		var t_Payload map[string]interface{}
+		if err = json.Unmarshal(content, &record); err == nil {
+			err = json.Unmarshal(record.Request, &t_Payload)
+		}
+		if err != nil || len(record.Request) == 0 {
+			grpcLog.Errorln(filename, "=> not a dead-letter file")
+			failed++
+			continue
+
+		}
+
+		// Keep the order within a transaction, once one of it's events fails we leave the rest for the next repost
+		if failedTxn != nil && record.TransactionId == failedTxn {
+			skipped++
+			continue
+
+		}
+
+		Response, retries, err := vSink.Send(t_Payload, record.Request)
+		if err == nil {
+			io.Copy(io.Discard, Response.Body)
+			Response.Body.Close()
+		}
+
+		if err != nil || (Response.StatusCode != http.StatusOK && Response.StatusCode != http.StatusNoContent) {
+			failedTxn = record.TransactionId
+			failed++
+
+			status := ""
+			if Response != nil {
+				status = Response.Status
+			}
+			grpcLog.Infoln(name, "=> FAILED", status, err, "retries", retries)
+			continue
+
+		}
+
+		if err = os.Rename(filename, fmt.Sprintf("%s%s%s", repostedDir, pathSep, name)); err != nil {
+			grpcLog.Errorln("Rename error", err)
+
+		}
+		reposted++
+		grpcLog.Infoln(name, "=>", Response.Status)
+
+	}
+
+	vSink.Close()
+
+	grpcLog.Infoln("")
+	grpcLog.Infoln("Events Reposted               : ", reposted)
+	grpcLog.Infoln("Events Failed                 : ", failed)
+	grpcLog.Infoln("Events Skipped                : ", skipped)
+
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"cmd/types"
+)
+
+func TestDeadLetterNamePart(t *testing.T) {
+
+	tests := []struct {
+		value interface{}
+		want  string
+	}{
+		{value: "3a62f78c-e668-40c1-a988-54f7be6762b4", want: "3a62f78c-e668-40c1-a988-54f7be6762b4"},
+		{value: nil, want: "unknown"},
+		{value: "", want: "unknown"},
+		{value: 42, want: "42"},
+		{value: "txn:1/2\\3*?<>|\" 4", want: "txn_1_2_3_______4"},
+		{value: "PRPP01_Transaction1.v2", want: "PRPP01_Transaction1.v2"},
+	}
+
+	for _, tt := range tests {
+		if got := deadLetterNamePart(tt.value); got != tt.want {
+			t.Errorf("deadLetterNamePart(%v) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+// A memorySink answering statusCodes per eventType, 204 otherwise.
+type statusSink struct {
+	memorySink
+	statusCodes map[string]int
+}
+
+func (s *statusSink) Send(t_Payload map[string]interface{}, Bytes []byte) (*http.Response, int, error) {
+
+	Response, retries, err := s.memorySink.Send(t_Payload, Bytes)
+	if statusCode, ok := s.statusCodes[t_Payload["eventType"].(string)]; ok {
+		Response = sinkResponse(statusCode)
+	}
+
+	return Response, retries, err
+}
+
+// Once a request-to-pay phase event is dead-lettered the later events are dead-lettered unposted, in order.
+func TestRequestToPayDeadLettered(t *testing.T) {
+
+	tests := []struct {
+		name           string
+		statusCodes    map[string]int
+		rejectRate     int
+		wantPosted     []string
+		wantDeadLetter []string
+	}{
+		{
+			name:           "requestToPay rejected by the API",
+			statusCodes:    map[string]int{requestToPayEventType: http.StatusBadRequest},
+			wantPosted:     []string{requestToPayEventType},
+			wantDeadLetter: []string{requestToPayEventType, requestToPayResponseEventType, "paymentRT", "paymentNRT"},
+		},
+		{
+			name:           "requestToPay rejected by the API, request declined",
+			statusCodes:    map[string]int{requestToPayEventType: http.StatusBadRequest},
+			rejectRate:     100,
+			wantPosted:     []string{requestToPayEventType},
+			wantDeadLetter: []string{requestToPayEventType, requestToPayResponseEventType},
+		},
+		{
+			name:           "requestToPayResponse rejected by the API",
+			statusCodes:    map[string]int{requestToPayResponseEventType: http.StatusInternalServerError},
+			wantPosted:     []string{requestToPayEventType, requestToPayResponseEventType},
+			wantDeadLetter: []string{requestToPayResponseEventType, "paymentRT", "paymentNRT"},
+		},
+		{
+			name:       "all accepted",
+			wantPosted: []string{requestToPayEventType, requestToPayResponseEventType},
+		},
+	}
+
+	defer func(saved types.Tp_general) { vGeneral = saved }(vGeneral)
+	defer func(saved Sink) { vSink = saved }(vSink)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vGeneral = types.Tp_general{Call_fs_api: 1, RtpAcceptRate: 100 - tt.rejectRate, RtpRejectRate: tt.rejectRate, DeadLetter_path: t.TempDir()}
+			sink := &statusSink{statusCodes: tt.statusCodes}
+			vSink = sink
+
+			t_InboundPayment := map[string]interface{}{"transactionId": "t1", "eventId": "e1", "eventType": "paymentRT", "tenantId": "creditorBank", "localInstrument": "RTP-PBPX"}
+			t_OutboundPayment := map[string]interface{}{"transactionId": "t1", "eventId": "e2", "eventType": "paymentNRT", "tenantId": "debtorBank", "localInstrument": "RTP-PBPX"}
+			t_RequestToPay := constructRequestToPay(t_OutboundPayment, t_InboundPayment)
+
+			accepted, err := processRequestToPay(t_RequestToPay, t_OutboundPayment, t_InboundPayment, "1", "rpp", 0)
+			if err != nil || accepted != (len(tt.wantDeadLetter) == 0 && tt.rejectRate == 0) {
+				t.Fatalf("processRequestToPay() = %v, %v", accepted, err)
+			}
+
+			var posted []string
+			for _, event := range sink.Events() {
+				posted = append(posted, event.EventType)
+			}
+			if !reflect.DeepEqual(posted, tt.wantPosted) {
+				t.Errorf("posted %v, want %v", posted, tt.wantPosted)
+			}
+
+			// By name, as repost replays them
+			files, err := os.ReadDir(vGeneral.DeadLetter_path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, file := range files {
+				names = append(names, file.Name())
+			}
+			sort.Strings(names)
+
+			var deadLettered []string
+			for _, name := range names {
+				content, _ := os.ReadFile(vGeneral.DeadLetter_path + pathSep + name)
+				for _, eventType := range []string{requestToPayResponseEventType, requestToPayEventType, "paymentRT", "paymentNRT"} {
+					if strings.Contains(string(content), `"eventType": "`+eventType+`"`) {
+						deadLettered = append(deadLettered, eventType)
+						break
+					}
+				}
+			}
+			if !reflect.DeepEqual(deadLettered, tt.wantDeadLetter) {
+				t.Errorf("dead-lettered %v, want %v", deadLettered, tt.wantDeadLetter)
+			}
+		})
+	}
+}
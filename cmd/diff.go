@@ -0,0 +1,325 @@
+/*****************************************************************************
+*
+*	File			: diff.go
+*
+* 	Created			: 19 Oct 2026
+*
+*	Description		: Compare two runs, to see what changed in the engine's outcomes between releases (model graph /
+*					: config group versions).
+*
+*					: fs_producer diff <env> <run A> <run B>
+*
+*					: A run is a results_file, or a directory, in which case the outcomes are read from the -out.json
+*					: files (engineResponse_to_file = 1) and the latencies from a results file found in it, e.g. a
+*					: runs_path run directory (see runs.go).
+*					: Events are matched on scenario step + eventType + direction, reported are
+*					:	- events only in A or B
+*					:	- score deltas larger than diffScoreThreshold
+*					:	- rules newly fired / no longer firing, alerts raised / no longer raised
+*					:	- riskStatus changes
+*					:	- versions (modelGraph/configGroups) changes
+*					:	- p50/p99 latency shifts per eventType/direction, in %
+*
+*					: Exit code 1 when more than diffMaxChanges events changed, or when a p99 latency shifted by more
+*					: than diffLatencyThreshold % (0 => latency is reported only), else 0.
+*
+*	By				: George Leonard (georgelza@gmail.com)
+*
+*****************************************************************************/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+type runData struct {
+	outcomes  map[string]stepOutcome
+	latencies map[string]latencyResult // overall (tenant *) per eventType|direction
+}
+
+// What we read from a results_file.
+type resultsFile struct {
+	Latencies []latencyResult `json:"latencies"`
+	Outcomes  []stepOutcome   `json:"outcomes"`
+}
+
+// What we read from a -out.json file.
+type outFile struct {
+	Step          string         `json:"step"`
+	EventType     string         `json:"eventType"`
+	TransactionId interface{}    `json:"transactionId"`
+	Outcome       *engineOutcome `json:"outcome"`
+}
+
+func loadRun(path string) (*runData, error) {
+
+	run := &runData{outcomes: make(map[string]stepOutcome), latencies: make(map[string]latencyResult)}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return run, run.addResults(path, true)
+	}
+
+	err = filepath.WalkDir(path, func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(name, ".json") {
+			return err
+		}
+
+		if strings.HasSuffix(name, "-out.json") {
+			return run.addOutFile(name)
+		}
+
+		// Any other JSON file, a results file if it has latencies or outcomes
+		return run.addResults(name, false)
+	})
+
+	return run, err
+}
+
+func (run *runData) addResults(name string, required bool) error {
+
+	content, err := os.ReadFile(name)
+	if err != nil {
+		return err
+	}
+
+	var results resultsFile
+	if err = json.Unmarshal(content, &results); err != nil {
+		if required {
+			return fmt.Errorf("%s is not a results file: %s", name, err)
+		}
+		return nil
+	}
+
+	for _, o := range results.Outcomes {
+		run.outcomes[o.key()] = o
+	}
+	for _, l := range results.Latencies {
+		if l.Tenant == latencyOverall {
+			run.latencies[l.EventType+"|"+l.Direction] = l
+		}
+	}
+
+	return nil
+}
+
+func (run *runData) addOutFile(name string) error {
+
+	content, err := os.ReadFile(name)
+	if err != nil {
+		return err
+	}
+
+	var out outFile
+	if err = json.Unmarshal(content, &out); err != nil || out.Outcome == nil || out.Step == "" {
+		// Not scored (NRT), or written before outcomes/steps were recorded
+		return nil
+	}
+
+	// The -out.json files don't carry the direction, the scored events are inbound paymentRT or outbound addPayeeRT
+	direction := "inbound"
+	if out.EventType == "addPayeeRT" {
+		direction = "outbound"
+	}
+
+	o := stepOutcome{Step: out.Step, EventType: out.EventType, Direction: direction, TransactionId: fmt.Sprintf("%v", out.TransactionId), Outcome: out.Outcome}
+	run.outcomes[o.key()] = o
+
+	return nil
+}
+
+// The differences between two outcomes of the same step/event, none => nil.
+func diffOutcome(a *engineOutcome, b *engineOutcome) (changes []string) {
+
+	if delta := b.OverallScore - a.OverallScore; math.Abs(delta) > vGeneral.DiffScoreThreshold {
+		changes = append(changes, fmt.Sprintf("score %v -> %v (%+g)", a.OverallScore, b.OverallScore, delta))
+	}
+
+	if a.RiskStatus != b.RiskStatus {
+		changes = append(changes, fmt.Sprintf("riskStatus %s -> %s", a.RiskStatus, b.RiskStatus))
+	}
+
+	if added, removed := setDiff(a.TriggeredRules, b.TriggeredRules); len(added)+len(removed) > 0 {
+		if len(added) > 0 {
+			changes = append(changes, fmt.Sprintf("newly fired %v", added))
+		}
+		if len(removed) > 0 {
+			changes = append(changes, fmt.Sprintf("no longer firing %v", removed))
+		}
+	}
+
+	if added, removed := setDiff(a.AlertedRules, b.AlertedRules); len(added)+len(removed) > 0 {
+		if len(added) > 0 {
+			changes = append(changes, fmt.Sprintf("newly alerting %v", added))
+		}
+		if len(removed) > 0 {
+			changes = append(changes, fmt.Sprintf("no longer alerting %v", removed))
+		}
+	}
+
+	return changes
+}
+
+// In b not a, in a not b.
+func setDiff(a []string, b []string) (added []string, removed []string) {
+
+	inA := make(map[string]bool)
+	for _, s := range a {
+		inA[s] = true
+	}
+	inB := make(map[string]bool)
+	for _, s := range b {
+		inB[s] = true
+		if !inA[s] {
+			added = append(added, s)
+		}
+	}
+	for _, s := range a {
+		if !inB[s] {
+			removed = append(removed, s)
+		}
+	}
+
+	return added, removed
+}
+
+// % change from a to b, 0 when a is 0.
+func percentShift(a float64, b float64) float64 {
+
+	if a == 0 {
+		return 0
+	}
+
+	return (b - a) / a * 100
+}
+
+// fs_producer diff <env> <run A> <run B>
+func runDiff(args []string) {
+
+	if len(args) < 3 {
+		grpcLog.Fatalln("Usage: fs_producer diff <env> <run A> <run B>")
+
+	}
+
+	vGeneral = loadConfig(args[0])
+
+	runA, err := loadRun(args[1])
+	if err != nil {
+		grpcLog.Fatalln("Problem reading run A: ", err)
+
+	}
+	runB, err := loadRun(args[2])
+	if err != nil {
+		grpcLog.Fatalln("Problem reading run B: ", err)
+
+	}
+
+	grpcLog.Infoln("Run A                         :", args[1], len(runA.outcomes), "outcomes")
+	grpcLog.Infoln("Run B                         :", args[2], len(runB.outcomes), "outcomes")
+	grpcLog.Infoln("")
+
+	keys := make(map[string]bool)
+	for key := range runA.outcomes {
+		keys[key] = true
+	}
+	for key := range runB.outcomes {
+		keys[key] = true
+	}
+	sortedOutcomeKeys := sortedKeys(keys)
+	sort.SliceStable(sortedOutcomeKeys, func(i, j int) bool {
+		si := strings.SplitN(sortedOutcomeKeys[i], "|", 2)[0]
+		sj := strings.SplitN(sortedOutcomeKeys[j], "|", 2)[0]
+		return si != sj && stepLess(si, sj)
+	})
+
+	var changed, onlyA, onlyB, versionChanges int
+	for _, key := range sortedOutcomeKeys {
+		a, inA := runA.outcomes[key]
+		b, inB := runB.outcomes[key]
+
+		switch {
+		case !inB:
+			onlyA++
+			grpcLog.Infoln("Only in A                     :", key)
+
+		case !inA:
+			onlyB++
+			grpcLog.Infoln("Only in B                     :", key)
+
+		default:
+			if !reflect.DeepEqual(a.Outcome.Versions, b.Outcome.Versions) {
+				if versionChanges == 0 {
+					va, _ := json.Marshal(a.Outcome.Versions)
+					vb, _ := json.Marshal(b.Outcome.Versions)
+					grpcLog.Infoln("Versions changed              :", string(va), "->", string(vb))
+				}
+				versionChanges++
+			}
+
+			if changes := diffOutcome(a.Outcome, b.Outcome); len(changes) > 0 {
+				changed++
+				grpcLog.Infoln("Changed                       :", key)
+				for _, change := range changes {
+					grpcLog.Infoln("                              :", change)
+				}
+			}
+		}
+	}
+
+	// Latency, overall per eventType/direction
+	var latencyKeys []string
+	for key := range runA.latencies {
+		if _, ok := runB.latencies[key]; ok {
+			latencyKeys = append(latencyKeys, key)
+		}
+	}
+	sort.Strings(latencyKeys)
+
+	var latencyRegressions int
+	if len(latencyKeys) > 0 {
+		grpcLog.Infoln("")
+		grpcLog.Infoln("Latency shift (ms)            :")
+	}
+	for _, key := range latencyKeys {
+		a, b := runA.latencies[key].Service, runB.latencies[key].Service
+
+		p50 := percentShift(a.Percentiles["p50"], b.Percentiles["p50"])
+		p99 := percentShift(a.Percentiles["p99"], b.Percentiles["p99"])
+
+		flag := ""
+		if vGeneral.DiffLatencyThreshold > 0 && p99 > vGeneral.DiffLatencyThreshold {
+			latencyRegressions++
+			flag = "  <= exceeds diffLatencyThreshold"
+		}
+
+		grpcLog.Infof("%-29s : p50 %.2f -> %.2f (%+.1f%%) p99 %.2f -> %.2f (%+.1f%%)%s", strings.Replace(key, "|", " ", 1),
+			a.Percentiles["p50"], b.Percentiles["p50"], p50, a.Percentiles["p99"], b.Percentiles["p99"], p99, flag)
+	}
+
+	grpcLog.Infoln("")
+	grpcLog.Infoln("Events Compared               : ", len(keys)-onlyA-onlyB)
+	grpcLog.Infoln("Events Changed                : ", changed)
+	grpcLog.Infoln("Events Only in A / B          : ", onlyA, "/", onlyB)
+	grpcLog.Infoln("Versions Changed              : ", versionChanges)
+	grpcLog.Infoln("Latency Regressions           : ", latencyRegressions)
+
+	if changed+onlyA+onlyB > vGeneral.DiffMaxChanges || latencyRegressions > 0 {
+		grpcLog.Infoln("Result                        :  FAILED, differences exceed the thresholds")
+		os.Exit(1)
+
+	}
+	grpcLog.Infoln("Result                        :  PASSED")
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"cmd/types"
+)
+
+func TestSetDiff(t *testing.T) {
+
+	tests := []struct {
+		name        string
+		a           []string
+		b           []string
+		wantAdded   []string
+		wantRemoved []string
+	}{
+		{name: "both empty"},
+		{name: "same", a: []string{"R1", "R2"}, b: []string{"R2", "R1"}},
+		{name: "added", a: []string{"R1"}, b: []string{"R1", "R2"}, wantAdded: []string{"R2"}},
+		{name: "removed", a: []string{"R1", "R2"}, b: []string{"R2"}, wantRemoved: []string{"R1"}},
+		{name: "replaced", a: []string{"R1"}, b: []string{"R2"}, wantAdded: []string{"R2"}, wantRemoved: []string{"R1"}},
+		{name: "from nothing", b: []string{"R1", "R2"}, wantAdded: []string{"R1", "R2"}},
+		{name: "to nothing", a: []string{"R1", "R2"}, wantRemoved: []string{"R1", "R2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			added, removed := setDiff(tt.a, tt.b)
+			if !reflect.DeepEqual(added, tt.wantAdded) || !reflect.DeepEqual(removed, tt.wantRemoved) {
+				t.Errorf("setDiff() = %v, %v, want %v, %v", added, removed, tt.wantAdded, tt.wantRemoved)
+			}
+		})
+	}
+}
+
+func TestDiffOutcome(t *testing.T) {
+
+	outcome := func(score float64, riskStatus string, triggered []string, alerted []string) *engineOutcome {
+		return &engineOutcome{OverallScore: score, RiskStatus: riskStatus, TriggeredRules: triggered, AlertedRules: alerted}
+	}
+
+	tests := []struct {
+		name      string
+		threshold float64
+		a         *engineOutcome
+		b         *engineOutcome
+		want      []string
+	}{
+		{
+			name: "unchanged",
+			a:    outcome(60, "review", []string{"R1"}, []string{"R1"}),
+			b:    outcome(60, "review", []string{"R1"}, []string{"R1"}),
+		},
+		{
+			name: "score up",
+			a:    outcome(60, "review", nil, nil),
+			b:    outcome(80, "review", nil, nil),
+			want: []string{"score 60 -> 80 (+20)"},
+		},
+		{
+			name: "score down",
+			a:    outcome(60, "review", nil, nil),
+			b:    outcome(45.5, "review", nil, nil),
+			want: []string{"score 60 -> 45.5 (-14.5)"},
+		},
+		{
+			name:      "score within threshold",
+			threshold: 5,
+			a:         outcome(60, "review", nil, nil),
+			b:         outcome(64, "review", nil, nil),
+		},
+		{
+			name:      "score beyond threshold",
+			threshold: 5,
+			a:         outcome(60, "review", nil, nil),
+			b:         outcome(66, "review", nil, nil),
+			want:      []string{"score 60 -> 66 (+6)"},
+		},
+		{
+			name: "riskStatus",
+			a:    outcome(60, "review", nil, nil),
+			b:    outcome(60, "alert", nil, nil),
+			want: []string{"riskStatus review -> alert"},
+		},
+		{
+			name: "rules",
+			a:    outcome(60, "review", []string{"R1", "R2"}, []string{"R1"}),
+			b:    outcome(60, "review", []string{"R2", "R3"}, []string{"R3"}),
+			want: []string{"newly fired [R3]", "no longer firing [R1]", "newly alerting [R3]", "no longer alerting [R1]"},
+		},
+		{
+			name: "everything",
+			a:    outcome(0, "no-risk", nil, nil),
+			b:    outcome(30, "review", []string{"R1"}, []string{"R1"}),
+			want: []string{"score 0 -> 30 (+30)", "riskStatus no-risk -> review", "newly fired [R1]", "newly alerting [R1]"},
+		},
+	}
+
+	defer func(saved types.Tp_general) { vGeneral = saved }(vGeneral)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vGeneral.DiffScoreThreshold = tt.threshold
+
+			if got := diffOutcome(tt.a, tt.b); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffOutcome() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPercentShift(t *testing.T) {
+
+	tests := []struct {
+		a, b float64
+		want float64
+	}{
+		{100, 150, 50},
+		{100, 50, -50},
+		{100, 100, 0},
+		{0, 50, 0},
+	}
+
+	for _, tt := range tests {
+		if got := percentShift(tt.a, tt.b); got != tt.want {
+			t.Errorf("percentShift(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
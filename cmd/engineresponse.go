@@ -0,0 +1,292 @@
+/*****************************************************************************
+*
+*	File			: engineresponse.go
+*
+* 	Created			: 19 Oct 2026
+*
+*	Description		: engineResponse parsing, into the typed model (types.TEngineResponse) and a outcome summary per
+*					: event, used for logging, metrics (fs_engine_rule_hits_total), the -out.json files and reports.
+*
+*					: overallScore		the highest entity overallScore (as RiskScoreExtract used to)
+*					: riskStatus		the most severe entity riskStatus (alert > review > no-risk)
+*					: triggeredRules	configGroups[].triggeredRules and aggregator scores.rules, all entities
+*					: alertedRules		rules of aggregators with alert and not suppressAlert, rule id from
+*					:					scores.rules, else the aggregatorId ("Rule: <id>")
+*
+*	By				: George Leonard (georgelza@gmail.com)
+*
+*****************************************************************************/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"cmd/types"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type entityOutcome struct {
+	TenantId       string   `json:"tenantId,omitempty"`
+	EntityType     string   `json:"entityType"`
+	EntityId       string   `json:"entityId"`
+	OverallScore   *float64 `json:"overallScore"`
+	RiskStatus     string   `json:"riskStatus,omitempty"`
+	TriggeredRules []string `json:"triggeredRules,omitempty"`
+	AlertedRules   []string `json:"alertedRules,omitempty"`
+}
+
+type engineOutcome struct {
+	OverallScore   float64          `json:"overallScore"`
+	RiskStatus     string           `json:"riskStatus,omitempty"`
+	TriggeredRules []string         `json:"triggeredRules"`
+	AlertedRules   []string         `json:"alertedRules"`
+	Entities       []entityOutcome  `json:"entities"`
+	Versions       *types.TVersions `json:"versions,omitempty"`
+}
+
+// A event's outcome within the run, keyed by scenario step, the results_file "outcomes"
+type stepOutcome struct {
+	Step          string         `json:"step"`
+	EventType     string         `json:"eventType"`
+	Direction     string         `json:"direction"`
+	TransactionId string         `json:"transactionId"`
+	Outcome       *engineOutcome `json:"outcome"`
+}
+
+func (o stepOutcome) key() string {
+	return fmt.Sprintf("%s|%s|%s", o.Step, o.EventType, o.Direction)
+}
+
+// Outcomes / rule matrix rows kept for record number steps (json_from_file = 0), a soak run on fake data has a
+// step per transaction, scenario file steps are bounded by input_path.
+const maxRecordSteps = 10000
+
+var (
+	outcomesMu      sync.Mutex
+	vOutcomes       = make(map[string]stepOutcome)
+	vOutcomesCapped bool
+)
+
+// Severity of the known riskStatus values, anything else ranks just above no-risk
+var riskStatusRank = map[string]int{
+	"":        0,
+	"no-risk": 1,
+	"review":  3,
+	"alert":   4,
+}
+
+func riskRank(riskStatus string) int {
+
+	if rank, ok := riskStatusRank[riskStatus]; ok {
+		return rank
+	}
+
+	return 2
+}
+
+func parseEngineResponse(body []byte) (*types.TEngineResponse, error) {
+
+	var engineResponse types.TEngineResponse
+	if err := json.Unmarshal(body, &engineResponse); err != nil {
+		return nil, fmt.Errorf("engineResponse unmarshal error: %s", err)
+	}
+
+	if engineResponse.Entities == nil {
+		return nil, errors.New("error accessing entities")
+	}
+
+	return &engineResponse, nil
+}
+
+// Parse and summarise a engineResponse body.
+func engineOutcomeOf(body []byte) (*engineOutcome, error) {
+
+	engineResponse, err := parseEngineResponse(body)
+	if err != nil {
+		return nil, err
+	}
+
+	return summariseEngineResponse(engineResponse), nil
+}
+
+func summariseEngineResponse(engineResponse *types.TEngineResponse) *engineOutcome {
+
+	outcome := &engineOutcome{Versions: engineResponse.Versions}
+
+	triggered := make(map[string]bool)
+	alerted := make(map[string]bool)
+
+	for _, entity := range engineResponse.Entities {
+
+		e := entityOutcome{
+			TenantId:   entity.TenantId,
+			EntityType: entity.EntityType,
+			EntityId:   entity.EntityId,
+			RiskStatus: entity.RiskStatus,
+		}
+		if entity.OverallScore != nil && entity.OverallScore.OverallScore != nil {
+			e.OverallScore = entity.OverallScore.OverallScore
+			if *e.OverallScore > outcome.OverallScore {
+				outcome.OverallScore = *e.OverallScore
+			}
+		}
+
+		if riskRank(entity.RiskStatus) > riskRank(outcome.RiskStatus) {
+			outcome.RiskStatus = entity.RiskStatus
+		}
+
+		entityTriggered := make(map[string]bool)
+		entityAlerted := make(map[string]bool)
+
+		for _, group := range entity.ConfigGroups {
+			for _, ruleId := range group.TriggeredRules {
+				entityTriggered[ruleId] = true
+			}
+
+			for _, aggregator := range group.Aggregators {
+				var ruleIds []string
+				if aggregator.Scores != nil {
+					for _, rule := range aggregator.Scores.Rules {
+						ruleIds = append(ruleIds, rule.RuleId)
+						entityTriggered[rule.RuleId] = true
+					}
+				}
+				if len(ruleIds) == 0 && strings.HasPrefix(aggregator.AggregatorId, "Rule: ") {
+					ruleIds = append(ruleIds, strings.TrimPrefix(aggregator.AggregatorId, "Rule: "))
+				}
+
+				if aggregator.Alert && !aggregator.SuppressAlert {
+					for _, ruleId := range ruleIds {
+						entityAlerted[ruleId] = true
+					}
+				}
+			}
+		}
+
+		e.TriggeredRules = sortedKeys(entityTriggered)
+		e.AlertedRules = sortedKeys(entityAlerted)
+		for _, ruleId := range e.TriggeredRules {
+			triggered[ruleId] = true
+		}
+		for _, ruleId := range e.AlertedRules {
+			alerted[ruleId] = true
+		}
+
+		outcome.Entities = append(outcome.Entities, e)
+	}
+
+	outcome.TriggeredRules = sortedKeys(triggered)
+	outcome.AlertedRules = sortedKeys(alerted)
+
+	return outcome
+}
+
+func sortedKeys(set map[string]bool) []string {
+
+	keys := []string{}
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// Debug logging, the rule hit metrics and matrix, and the run's outcomes.
+func reportEngineOutcome(label string, t_Payload map[string]interface{}, direction string, outcome *engineOutcome) {
+
+	if outcome == nil {
+		return
+	}
+
+	recordRuleHits(t_Payload, outcome)
+	recordOutcome(t_Payload, direction, outcome)
+
+	if vGeneral.Debuglevel > 2 {
+		grpcLog.Infoln(label, "riskStatus", outcome.RiskStatus, "triggeredRules", outcome.TriggeredRules, "alertedRules", outcome.AlertedRules)
+
+		for _, e := range outcome.Entities {
+			score := "null"
+			if e.OverallScore != nil {
+				score = fmt.Sprintf("%v", *e.OverallScore)
+			}
+			grpcLog.Infof("  %-20s %-40s score %-6s %-8s %v", e.EntityType, e.EntityId, score, e.RiskStatus, e.TriggeredRules)
+		}
+	}
+
+	if vGeneral.Prometheus_enabled == 1 {
+		alerted := make(map[string]bool)
+		for _, ruleId := range outcome.AlertedRules {
+			alerted[ruleId] = true
+		}
+
+		for _, ruleId := range outcome.TriggeredRules {
+			m.engine_rule_hits.With(prometheus.Labels{
+				"hostname": vGeneral.Hostname,
+				"msg_type": fmt.Sprintf("%v", t_Payload["eventType"]),
+				"rule":     ruleId,
+				"alert":    fmt.Sprintf("%v", alerted[ruleId])}).Inc()
+		}
+	}
+}
+
+// The outcomes are only kept for the results_file, golden baseline and report.
+func outcomesWanted() bool {
+	return vGeneral.Results_file != "" || vGeneral.Report_file != "" || vGeneral.Golden == 1 || vUpdateGolden
+}
+
+// Keep the outcome, the last one per step/event (soak runs cycling through the scenario files).
+func recordOutcome(t_Payload map[string]interface{}, direction string, outcome *engineOutcome) {
+
+	if !outcomesWanted() {
+		return
+	}
+
+	_, step := cassetteKey(t_Payload)
+
+	o := stepOutcome{
+		Step:          step,
+		EventType:     fmt.Sprintf("%v", t_Payload["eventType"]),
+		Direction:     direction,
+		TransactionId: fmt.Sprintf("%v", t_Payload["transactionId"]),
+		Outcome:       outcome,
+	}
+
+	outcomesMu.Lock()
+	defer outcomesMu.Unlock()
+
+	if _, ok := vOutcomes[o.key()]; !ok && vGeneral.Json_from_file != 1 && len(vOutcomes) >= maxRecordSteps {
+		if !vOutcomesCapped {
+			vOutcomesCapped = true
+			grpcLog.Warningln("Outcomes capped at            :", maxRecordSteps, "record steps, later records not kept")
+		}
+		return
+	}
+	vOutcomes[o.key()] = o
+}
+
+// All outcomes, in step order.
+func runOutcomes() (outcomes []stepOutcome) {
+
+	outcomesMu.Lock()
+	defer outcomesMu.Unlock()
+
+	for _, o := range vOutcomes {
+		outcomes = append(outcomes, o)
+	}
+	sort.Slice(outcomes, func(i, j int) bool {
+		if outcomes[i].Step != outcomes[j].Step {
+			return stepLess(outcomes[i].Step, outcomes[j].Step)
+		}
+		return outcomes[i].key() < outcomes[j].key()
+	})
+
+	return outcomes
+}
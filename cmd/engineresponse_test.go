@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"cmd/types"
+)
+
+// The engineResponse of a -out.json file, as written by processTransaction.
+func outFileResponse(t *testing.T, filename string) []byte {
+
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var outFile struct {
+		ResponseBody json.RawMessage `json:"responseBody"`
+	}
+	if err = json.Unmarshal(content, &outFile); err != nil {
+		t.Fatalf("%s: %s", filename, err)
+	}
+
+	return outFile.ResponseBody
+}
+
+func TestEngineOutcomeOf(t *testing.T) {
+
+	tests := []struct {
+		name          string
+		file          string // a sample -out.json, else body
+		body          string
+		wantErr       bool
+		wantScore     float64
+		wantRisk      string
+		wantTriggered []string
+		wantAlerted   []string
+		wantEntities  int
+	}{
+		{
+			name:          "paymentRT alert sample",
+			file:          "../misc/1_PRPP01_Transaction1-f14797ab-1fda-43ce-a65e-4ad6b2b51741-d4f434e7-83f1-4230-b11e-b420130d5877-out.json",
+			wantScore:     60,
+			wantRisk:      "review",
+			wantTriggered: []string{"BRPP08_Unusual_Behaviour_During_Probation_Period", "PRPP01_Multiple_RPP_CRTRF"},
+			wantAlerted:   []string{"BRPP08_Unusual_Behaviour_During_Probation_Period", "PRPP01_Multiple_RPP_CRTRF"},
+			wantEntities:  6,
+		},
+		{
+			name:          "no-risk sample",
+			file:          "../x/1_3a62f78c-e668-40c1-a988-54f7be6762b4-09164d39-b54e-4c3b-82bb-6f61f47b2a3d-out.json",
+			wantRisk:      "no-risk",
+			wantTriggered: []string{},
+			wantAlerted:   []string{},
+			wantEntities:  6,
+		},
+		{
+			name: "suppressed alert and aggregatorId fallback",
+			body: `{"entities": [{"entityType": "ACCOUNT", "entityId": "A1", "riskStatus": "alert", "overallScore": {"overallScore": 80},
+				"configGroups": [{"triggeredRules": ["R1"], "aggregators": [
+					{"aggregatorId": "Rule: R2", "alert": true},
+					{"aggregatorId": "Rule: R3", "alert": true, "suppressAlert": true, "scores": {"rules": [{"ruleId": "R3", "score": 1}]}}]}]},
+				{"entityType": "COUNTERPARTY", "entityId": "C1", "riskStatus": "review", "overallScore": {"overallScore": 90}}]}`,
+			wantScore:     90,
+			wantRisk:      "alert",
+			wantTriggered: []string{"R1", "R3"},
+			wantAlerted:   []string{"R2"},
+			wantEntities:  2,
+		},
+		{
+			name:          "unknown riskStatus above no-risk",
+			body:          `{"entities": [{"entityType": "ACCOUNT", "riskStatus": "no-risk"}, {"entityType": "COUNTERPARTY", "riskStatus": "watch"}]}`,
+			wantRisk:      "watch",
+			wantTriggered: []string{},
+			wantAlerted:   []string{},
+			wantEntities:  2,
+		},
+		{
+			name:    "no entities",
+			body:    `{"jsonVersion": 4}`,
+			wantErr: true,
+		},
+		{
+			name:    "not json",
+			body:    `paymentNRT`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := []byte(tt.body)
+			if tt.file != "" {
+				body = outFileResponse(t, tt.file)
+			}
+
+			outcome, err := engineOutcomeOf(body)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("engineOutcomeOf() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if outcome.OverallScore != tt.wantScore || outcome.RiskStatus != tt.wantRisk {
+				t.Errorf("engineOutcomeOf() score %v riskStatus %q, want %v %q", outcome.OverallScore, outcome.RiskStatus, tt.wantScore, tt.wantRisk)
+			}
+			if !reflect.DeepEqual(outcome.TriggeredRules, tt.wantTriggered) {
+				t.Errorf("triggeredRules = %v, want %v", outcome.TriggeredRules, tt.wantTriggered)
+			}
+			if !reflect.DeepEqual(outcome.AlertedRules, tt.wantAlerted) {
+				t.Errorf("alertedRules = %v, want %v", outcome.AlertedRules, tt.wantAlerted)
+			}
+			if len(outcome.Entities) != tt.wantEntities {
+				t.Errorf("entities = %d, want %d", len(outcome.Entities), tt.wantEntities)
+			}
+		})
+	}
+}
+
+// Outcomes are only kept when used, record number steps up to maxRecordSteps.
+func TestRecordOutcome(t *testing.T) {
+
+	tests := []struct {
+		name         string
+		general      types.Tp_general
+		records      int
+		wantOutcomes int
+		wantCapped   bool
+	}{
+		{name: "nothing uses them", records: 10},
+		{name: "results file", general: types.Tp_general{Results_file: "results.json"}, records: 10, wantOutcomes: 10},
+		{name: "golden", general: types.Tp_general{Golden: 1}, records: 10, wantOutcomes: 10},
+		{name: "report, record steps capped", general: types.Tp_general{Report_file: "report.html"}, records: maxRecordSteps + 10, wantOutcomes: maxRecordSteps, wantCapped: true},
+		{name: "scenario files not capped", general: types.Tp_general{Results_file: "results.json", Json_from_file: 1}, records: maxRecordSteps + 10, wantOutcomes: maxRecordSteps + 10},
+	}
+
+	defer func(saved types.Tp_general) { vGeneral = saved }(vGeneral)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vGeneral = tt.general
+			vOutcomes = make(map[string]stepOutcome)
+			vOutcomesCapped = false
+
+			for i := 0; i < tt.records; i++ {
+				transactionId := fmt.Sprintf("t%d", i)
+				setScenarioStep(transactionId, strconv.Itoa(i))
+				recordOutcome(map[string]interface{}{"eventType": "paymentRT", "transactionId": transactionId}, "inbound", &engineOutcome{})
+				clearScenarioStep(transactionId)
+			}
+
+			if got := len(runOutcomes()); got != tt.wantOutcomes || vOutcomesCapped != tt.wantCapped {
+				t.Errorf("outcomes = %d capped %v, want %d capped %v", got, vOutcomesCapped, tt.wantOutcomes, tt.wantCapped)
+			}
+		})
+	}
+	vOutcomes = make(map[string]stepOutcome)
+}
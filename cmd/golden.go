@@ -0,0 +1,184 @@
+/*****************************************************************************
+*
+*	File			: golden.go
+*
+* 	Created			: 19 Oct 2026
+*
+*	Description		: Golden baselines, a blessed snapshot of the scenario outcomes, triggered rules, alerted rules,
+*					: riskStatus and the score rounded to goldenScoreDecimals, per scenario step + eventType +
+*					: direction. Lighter than explicit assertions for every legacy scenario.
+*
+*					: golden = 1, at the end of the run every outcome is compared against golden_file, a difference
+*					: is a regression (the run exits 1), a outcome without a baseline is reported as not blessed.
+*					: A golden_file that can't be read/parsed (or written when blessing) also fails the run.
+*					: Score differences up to diffScoreThreshold are ignored, as for "fs_producer diff".
+*
+*					: fs_producer <env> --update-golden
+*					: (re-)blesses the run, the outcomes of this run replace their baseline entries, entries for steps
+*					: not in this run are kept.
+*
+*					: golden_file "" => <input_path>.golden.json, next to the scenario directory.
+*
+*	By				: George Leonard (georgelza@gmail.com)
+*
+*****************************************************************************/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"cmd/types"
+)
+
+var vUpdateGolden bool
+
+type goldenOutcome struct {
+	Score          float64  `json:"score"`
+	RiskStatus     string   `json:"riskStatus"`
+	TriggeredRules []string `json:"triggeredRules"`
+	AlertedRules   []string `json:"alertedRules"`
+}
+
+type goldenBaseline struct {
+	Blessed  time.Time                `json:"blessed"`
+	Versions *types.TVersions         `json:"versions,omitempty"`
+	Outcomes map[string]goldenOutcome `json:"outcomes"`
+}
+
+func goldenFile() string {
+
+	if vGeneral.Golden_file != "" {
+		return vGeneral.Golden_file
+	}
+	if vGeneral.Input_path != "" {
+		return strings.TrimRight(vGeneral.Input_path, pathSep) + ".golden.json"
+	}
+
+	return ""
+}
+
+func goldenOf(outcome *engineOutcome) goldenOutcome {
+
+	scale := math.Pow(10, float64(vGeneral.GoldenScoreDecimals))
+
+	return goldenOutcome{
+		Score:          math.Round(outcome.OverallScore*scale) / scale,
+		RiskStatus:     outcome.RiskStatus,
+		TriggeredRules: append([]string{}, outcome.TriggeredRules...),
+		AlertedRules:   append([]string{}, outcome.AlertedRules...),
+	}
+}
+
+func (g goldenOutcome) outcome() *engineOutcome {
+
+	return &engineOutcome{
+		OverallScore:   g.Score,
+		RiskStatus:     g.RiskStatus,
+		TriggeredRules: g.TriggeredRules,
+		AlertedRules:   g.AlertedRules,
+	}
+}
+
+func loadGolden(filename string) (*goldenBaseline, error) {
+
+	baseline := &goldenBaseline{Outcomes: make(map[string]goldenOutcome)}
+
+	content, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return baseline, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(content, baseline); err != nil {
+		return nil, err
+	}
+	if baseline.Outcomes == nil {
+		baseline.Outcomes = make(map[string]goldenOutcome)
+	}
+
+	return baseline, nil
+}
+
+// Bless (--update-golden) or compare the run against the baseline, returns the number of regressions. err is set if
+// the baseline could not be checked/blessed at all.
+func checkGolden() (regressions int, err error) {
+
+	if vGeneral.Golden != 1 && !vUpdateGolden {
+		return 0, nil
+	}
+
+	filename := goldenFile()
+	if filename == "" {
+		return 0, errors.New("golden baseline requires golden_file (or json_from_file = 1)")
+
+	}
+
+	baseline, err := loadGolden(filename)
+	if err != nil {
+		return 0, fmt.Errorf("golden baseline read error %s: %s", filename, err)
+
+	}
+
+	outcomes := runOutcomes()
+
+	grpcLog.Infoln("")
+
+	if vUpdateGolden {
+		for _, o := range outcomes {
+			baseline.Outcomes[o.key()] = goldenOf(o.Outcome)
+			if o.Outcome.Versions != nil {
+				baseline.Versions = o.Outcome.Versions
+			}
+		}
+		baseline.Blessed = time.Now()
+
+		fj, err := json.MarshalIndent(baseline, "", " ")
+		if err == nil {
+			err = os.WriteFile(filename, fj, 0644)
+		}
+		if err != nil {
+			return 0, fmt.Errorf("golden baseline write error %s: %s", filename, err)
+
+		}
+
+		grpcLog.Infoln("Golden baseline blessed       :", filename, len(outcomes), "outcomes,", len(baseline.Outcomes), "in total")
+		return 0, nil
+	}
+
+	var unblessed int
+	for _, o := range outcomes {
+		golden, ok := baseline.Outcomes[o.key()]
+		if !ok {
+			unblessed++
+			if vGeneral.Debuglevel > 1 {
+				grpcLog.Infoln("Golden not blessed            :", o.key())
+
+			}
+			continue
+		}
+
+		if changes := diffOutcome(golden.outcome(), goldenOf(o.Outcome).outcome()); len(changes) > 0 {
+			regressions++
+			grpcLog.Warningln("Golden regression             :", o.key(), o.TransactionId)
+			for _, change := range changes {
+				grpcLog.Warningln("                              :", change)
+			}
+		}
+	}
+
+	grpcLog.Infoln("Golden baseline               :", filename, "blessed", baseline.Blessed.Format(time.RFC3339))
+	grpcLog.Infoln("Golden Compared               : ", len(outcomes)-unblessed, "of", len(baseline.Outcomes))
+	grpcLog.Infoln("Golden Not Blessed            : ", unblessed)
+	grpcLog.Infoln("Golden Regressions            : ", regressions)
+
+	return regressions, nil
+}
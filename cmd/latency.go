@@ -0,0 +1,235 @@
+/*****************************************************************************
+*
+*	File			: latency.go
+*
+* 	Created			: 19 Oct 2026
+*
+*	Description		: Latency percentiles, every API call is recorded (microseconds) into a HDR histogram per
+*					: eventType, direction and tenant. At the end of the run p50/p90/p95/p99/p99.9/max are printed and,
+*					: if results_file is set, written as JSON, together with the engineResponse outcomes per scenario
+*					: step (see engineresponse.go), the input for "fs_producer diff".
+*
+*					: Open-loop (rateProfile set), we also record the latency corrected for coordinated omission, the
+*					: time from when the transaction was scheduled to start, not when a worker got to it, plus the call
+*					: time. When the workers fall behind the corrected figures show what a real client would have seen.
+*
+*					: Anything above 10 min (latencyMax) is recorded as 10 min and counted as clamped, the max and top
+*					: percentiles are then a lower bound.
+*
+*	By				: George Leonard (georgelza@gmail.com)
+*
+*****************************************************************************/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	hdrhistogram "github.com/HdrHistogram/hdrhistogram-go"
+)
+
+// 1 µs -> 10 min, 3 significant digits
+const (
+	latencyMin     = 1
+	latencyMax     = 600000000
+	latencyDigits  = 3
+	latencyOverall = "*"
+)
+
+var latencyPercentiles = []float64{50, 90, 95, 99, 99.9}
+
+type latencyKey struct {
+	EventType string
+	Direction string
+	Tenant    string
+}
+
+type latencyHistograms struct {
+	service          *hdrhistogram.Histogram // call time as measured
+	corrected        *hdrhistogram.Histogram // open-loop, incl. the time the transaction waited for a worker
+	serviceClamped   int64                   // values above latencyMax, recorded as latencyMax
+	correctedClamped int64
+}
+
+var (
+	latencyMu  sync.Mutex
+	vLatencies = make(map[latencyKey]*latencyHistograms)
+)
+
+// Record a API call, lag is how late the transaction started against it's schedule (open-loop).
+func recordLatency(t_Payload map[string]interface{}, direction string, callTime time.Duration, lag time.Duration) {
+
+	key := latencyKey{
+		EventType: fmt.Sprintf("%v", t_Payload["eventType"]),
+		Direction: direction,
+		Tenant:    fmt.Sprintf("%v", t_Payload["tenantId"]),
+	}
+
+	if lag < 0 {
+		lag = 0
+	}
+
+	recordTimeline(key.EventType, direction, callTime)
+
+	latencyMu.Lock()
+	defer latencyMu.Unlock()
+
+	// Per eventType/direction/tenant, plus a overall per eventType/direction
+	for _, k := range []latencyKey{key, {EventType: key.EventType, Direction: key.Direction, Tenant: latencyOverall}} {
+		h, ok := vLatencies[k]
+		if !ok {
+			h = &latencyHistograms{
+				service:   hdrhistogram.New(latencyMin, latencyMax, latencyDigits),
+				corrected: hdrhistogram.New(latencyMin, latencyMax, latencyDigits),
+			}
+			vLatencies[k] = h
+		}
+		recordClamped(h.service, &h.serviceClamped, callTime)
+		recordClamped(h.corrected, &h.correctedClamped, callTime+lag)
+	}
+}
+
+// Values above latencyMax would be dropped by the histogram, the worst samples of a run that fell behind.
+func recordClamped(h *hdrhistogram.Histogram, clamped *int64, value time.Duration) {
+
+	us := value.Microseconds()
+	if us > latencyMax {
+		us = latencyMax
+		*clamped++
+	}
+
+	h.RecordValue(us)
+}
+
+// The figures for one histogram, in milliseconds.
+type latencySummary struct {
+	Count       int64              `json:"count"`
+	Mean        float64            `json:"mean"`
+	Percentiles map[string]float64 `json:"percentiles"`
+	Max         float64            `json:"max"`
+	Clamped     int64              `json:"clamped,omitempty"` // above latencyMax, recorded as latencyMax
+}
+
+type latencyResult struct {
+	EventType string          `json:"eventType"`
+	Direction string          `json:"direction"`
+	Tenant    string          `json:"tenant"`
+	Service   latencySummary  `json:"service"`
+	Corrected *latencySummary `json:"corrected,omitempty"`
+}
+
+func summarise(h *hdrhistogram.Histogram, clamped int64) latencySummary {
+
+	s := latencySummary{
+		Count:       h.TotalCount(),
+		Mean:        h.Mean() / 1000,
+		Percentiles: make(map[string]float64),
+		Max:         float64(h.Max()) / 1000,
+		Clamped:     clamped,
+	}
+	for _, p := range latencyPercentiles {
+		s.Percentiles[fmt.Sprintf("p%g", p)] = float64(h.ValueAtQuantile(p)) / 1000
+	}
+
+	return s
+}
+
+// All histograms, overall per eventType/direction first, then per tenant.
+func latencyResults() (results []latencyResult) {
+
+	latencyMu.Lock()
+	defer latencyMu.Unlock()
+
+	for k, h := range vLatencies {
+		r := latencyResult{
+			EventType: k.EventType,
+			Direction: k.Direction,
+			Tenant:    k.Tenant,
+			Service:   summarise(h.service, h.serviceClamped),
+		}
+		if vGeneral.RateProfile != "" {
+			corrected := summarise(h.corrected, h.correctedClamped)
+			r.Corrected = &corrected
+		}
+		results = append(results, r)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if (a.Tenant == latencyOverall) != (b.Tenant == latencyOverall) {
+			return a.Tenant == latencyOverall
+		}
+		if a.EventType != b.EventType {
+			return a.EventType < b.EventType
+		}
+		if a.Direction != b.Direction {
+			return a.Direction < b.Direction
+		}
+		return a.Tenant < b.Tenant
+	})
+
+	return results
+}
+
+func formatLatency(s latencySummary) string {
+
+	line := fmt.Sprintf("n=%d p50=%.2f p90=%.2f p95=%.2f p99=%.2f p99.9=%.2f max=%.2f ms",
+		s.Count, s.Percentiles["p50"], s.Percentiles["p90"], s.Percentiles["p95"], s.Percentiles["p99"], s.Percentiles["p99.9"], s.Max)
+	if s.Clamped > 0 {
+		line += fmt.Sprintf(", %d clamped at %.0f ms", s.Clamped, float64(latencyMax)/1000)
+	}
+
+	return line
+}
+
+// Print the percentiles, per tenant only at debuglevel > 1, and write the results file.
+func reportLatencies() {
+
+	results := latencyResults()
+	if len(results) == 0 {
+		return
+	}
+
+	grpcLog.Infoln("")
+	grpcLog.Infoln("API Call Latency (ms)         :")
+	for _, r := range results {
+		if r.Tenant != latencyOverall && vGeneral.Debuglevel <= 1 {
+			continue
+		}
+
+		grpcLog.Infof("%-20s %-8s %-8s : %s", r.EventType, r.Direction, r.Tenant, formatLatency(r.Service))
+		if r.Corrected != nil {
+			grpcLog.Infof("%-20s %-8s %-8s : %s (corrected)", "", "", "", formatLatency(*r.Corrected))
+
+		}
+	}
+
+	if vGeneral.Results_file == "" {
+		return
+	}
+
+	fj, err := json.MarshalIndent(map[string]interface{}{
+		"hostname":    vGeneral.Hostname,
+		"rateProfile": vGeneral.RateProfile,
+		"workers":     vGeneral.Workers,
+		"latencies":   results,
+		"outcomes":    runOutcomes(),
+	}, "", " ")
+	if err != nil {
+		grpcLog.Errorln("MarshalIndent error", err)
+
+	}
+
+	err = os.WriteFile(vGeneral.Results_file, fj, 0644)
+	if err != nil {
+		grpcLog.Errorln("os.WriteFile error", err)
+
+	}
+	grpcLog.Infoln("Results written to            :", vGeneral.Results_file)
+
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"cmd/types"
+)
+
+// Latencies beyond latencyMax are recorded as latencyMax and counted, not dropped.
+func TestRecordLatencyClamped(t *testing.T) {
+
+	tests := []struct {
+		name          string
+		callTime      time.Duration
+		lag           time.Duration
+		wantService   int64
+		wantCorrected int64
+	}{
+		{name: "within range", callTime: 200 * time.Millisecond, lag: time.Minute},
+		{name: "corrected beyond range", callTime: time.Second, lag: 15 * time.Minute, wantCorrected: 1},
+		{name: "both beyond range", callTime: 11 * time.Minute, lag: time.Minute, wantService: 1, wantCorrected: 1},
+	}
+
+	defer func(saved types.Tp_general) { vGeneral = saved }(vGeneral)
+	vGeneral = types.Tp_general{RateProfile: "constant"}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vLatencies = make(map[latencyKey]*latencyHistograms)
+
+			recordLatency(map[string]interface{}{"eventType": "paymentRT", "tenantId": "tn1"}, "inbound", tt.callTime, tt.lag)
+
+			for _, r := range latencyResults() {
+				if r.Service.Count != 1 || r.Corrected.Count != 1 {
+					t.Fatalf("%s count = %d/%d, want 1/1", r.Tenant, r.Service.Count, r.Corrected.Count)
+				}
+				if r.Service.Clamped != tt.wantService || r.Corrected.Clamped != tt.wantCorrected {
+					t.Errorf("%s clamped = %d/%d, want %d/%d", r.Tenant, r.Service.Clamped, r.Corrected.Clamped, tt.wantService, tt.wantCorrected)
+				}
+				if tt.wantCorrected > 0 && r.Corrected.Max < float64(latencyMax)/1000*0.999 {
+					t.Errorf("%s corrected max = %v, want ~%v", r.Tenant, r.Corrected.Max, float64(latencyMax)/1000)
+				}
+			}
+		})
+	}
+	vLatencies = make(map[latencyKey]*latencyHistograms)
+}
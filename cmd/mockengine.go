@@ -0,0 +1,306 @@
+/*****************************************************************************
+*
+*	File			: mockengine.go
+*
+* 	Created			: 19 Oct 2026
+*
+*	Description		: Local mock of the FeatureSpace engine /events endpoint, so the full pipeline (scores, RTP,
+*					: engineResponse files, metrics) can be developed and demoed without SIT access.
+*
+*					: fs_producer mock-engine <env> [address]
+*
+*					: paymentRT/addPayeeRT => 200 with a engineResponse, shaped as per x/engineResponse_outbound.json,
+*					: all other events (paymentNRT, addPayeeNRT, requestToPay...) => 204 No Content.
+*
+*					: mockEngineAddress		listen address, default 127.0.0.1:18080
+*					: mockEngineTls			0/1, 1 => HTTPS using mockEngineCert_file/mockEngineKey_file (in cert_dir), client
+*					:						certificates required, verified against ca_file if set (mTLS)
+*					: mockLatencyMin/Max	ms, each request is delayed a random min -> max
+*					: mockErrorRate			0 -> 1, fraction of requests answered with mockErrorStatus (default 503)
+*					: mockRules				scoring rules, first match wins, see types.Tp_mockRule, no match => score 0,
+*					:						no triggered rules, riskStatus no-risk.
+*
+*	By				: George Leonard (georgelza@gmail.com)
+*
+*****************************************************************************/
+
+package main
+
+import (
+	"compress/gzip"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"cmd/types"
+)
+
+const defaultMockEngineAddress = "127.0.0.1:18080"
+
+// The real time events, answered with a engineResponse
+var mockScoredEvents = map[string]bool{
+	"paymentRT":  true,
+	"addPayeeRT": true,
+}
+
+// fs_producer mock-engine <env> [address]
+func runMockEngine(args []string) {
+
+	if len(args) < 1 {
+		grpcLog.Fatalln("Usage: fs_producer mock-engine <env> [address]")
+
+	}
+
+	vGeneral = loadConfig(args[0])
+
+	address := vGeneral.MockEngineAddress
+	if len(args) > 1 {
+		address = args[1]
+	}
+	if address == "" {
+		address = defaultMockEngineAddress
+	}
+
+	var requests int64
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/events", func(w http.ResponseWriter, r *http.Request) {
+
+		n := atomic.AddInt64(&requests, 1)
+
+		if r.Method != "POST" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		if vGeneral.MockLatencyMax > 0 {
+			delay := vGeneral.MockLatencyMin
+			if vGeneral.MockLatencyMax > vGeneral.MockLatencyMin {
+				delay += rand.Intn(vGeneral.MockLatencyMax - vGeneral.MockLatencyMin)
+			}
+			time.Sleep(time.Duration(delay) * time.Millisecond)
+		}
+
+		var body io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			zr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				mockError(w, http.StatusBadRequest, "invalid gzip body")
+				return
+			}
+			body = zr
+		}
+
+		var t_Event map[string]interface{}
+		if err := json.NewDecoder(body).Decode(&t_Event); err != nil {
+			mockError(w, http.StatusBadRequest, "invalid JSON body")
+			return
+		}
+
+		eventType, _ := t_Event["eventType"].(string)
+		if eventType == "" || t_Event["eventId"] == nil {
+			mockError(w, http.StatusBadRequest, "eventType and eventId are required")
+			return
+		}
+
+		if vGeneral.MockErrorRate > 0 && rand.Float64() < vGeneral.MockErrorRate {
+			mockError(w, orDefault(vGeneral.MockErrorStatus, http.StatusServiceUnavailable), "injected error")
+
+			if vGeneral.Debuglevel > 1 {
+				grpcLog.Infoln("Mock engine injected error    :", n, eventType, t_Event["eventId"])
+
+			}
+			return
+		}
+
+		if !mockScoredEvents[eventType] {
+			w.WriteHeader(http.StatusNoContent)
+
+			if vGeneral.Debuglevel > 1 {
+				grpcLog.Infoln("Mock engine                   :", n, eventType, t_Event["eventId"], "=> 204")
+
+			}
+			return
+		}
+
+		rule := matchMockRule(t_Event)
+		engineResponse := mockEngineResponse(t_Event, rule)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(engineResponse)
+
+		if vGeneral.Debuglevel > 1 {
+			grpcLog.Infoln("Mock engine                   :", n, eventType, t_Event["eventId"], "=> 200 score", rule.Score, rule.TriggeredRules)
+
+		}
+	})
+
+	server := &http.Server{Addr: address, Handler: mux}
+
+	if vGeneral.MockEngineTls == 1 {
+		tlsConfig, err := mockEngineTLSConfig()
+		if err != nil {
+			grpcLog.Fatalln("Mock engine TLS error: ", err)
+
+		}
+		server.TLSConfig = tlsConfig
+
+		grpcLog.Infoln("Mock engine listening on      :", "https://"+address+"/events")
+		err = server.ListenAndServeTLS(vGeneral.MockEngineCert_file, vGeneral.MockEngineKey_file)
+		grpcLog.Fatalln("Mock engine error: ", err)
+
+	}
+
+	grpcLog.Infoln("Mock engine listening on      :", "http://"+address+"/events")
+	err := server.ListenAndServe()
+	grpcLog.Fatalln("Mock engine error: ", err)
+
+}
+
+// Client certificates are always required, verified against ca_file when we have one.
+func mockEngineTLSConfig() (*tls.Config, error) {
+
+	if vGeneral.MockEngineCert_file == "" || vGeneral.MockEngineKey_file == "" {
+		return nil, fmt.Errorf("mockEngineTls requires mockEngineCert_file and mockEngineKey_file")
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ClientAuth: tls.RequireAnyClientCert,
+	}
+
+	if vGeneral.Ca_file != "" {
+		caPEM, err := os.ReadFile(vGeneral.Ca_file)
+		if err != nil {
+			return nil, fmt.Errorf("Problem reading: %s Error: %s", vGeneral.Ca_file, err)
+		}
+
+		caCertPool := x509.NewCertPool()
+		if !caCertPool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("no certificates found in %s", vGeneral.Ca_file)
+		}
+		tlsConfig.ClientCAs = caCertPool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+
+	}
+
+	return tlsConfig, nil
+}
+
+func mockError(w http.ResponseWriter, statusCode int, message string) {
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
+
+// First rule matching the event, else the zero rule (score 0, nothing triggered).
+func matchMockRule(t_Event map[string]interface{}) types.Tp_mockRule {
+
+	eventType, _ := t_Event["eventType"].(string)
+	tenantId, _ := t_Event["tenantId"].(string)
+
+	var value float64
+	if amount, ok := t_Event["amount"].(map[string]interface{}); ok {
+		value, _ = amount["value"].(float64)
+	}
+
+	for _, rule := range vGeneral.MockRules {
+		if rule.EventType != "" && rule.EventType != eventType {
+			continue
+		}
+		if rule.TenantId != "" && rule.TenantId != tenantId {
+			continue
+		}
+		if value < rule.MinValue || (rule.MaxValue > 0 && value > rule.MaxValue) {
+			continue
+		}
+		return rule
+	}
+
+	return types.Tp_mockRule{}
+}
+
+// <agentId>-<number>, or the proxy for proxy (PBPX) payments without a account number.
+func mockEntityId(t_Event map[string]interface{}, party string) string {
+
+	if number, ok := t_Event[party+"Number"]; ok && number != nil {
+		return fmt.Sprintf("%v-%v", t_Event[party+"AgentId"], number)
+	}
+
+	return fmt.Sprintf("%v", t_Event[party+"ProxyId"])
+}
+
+// The engineResponse, the scored ACCOUNT entity plus a unscored COUNTERPARTY.
+func mockEngineResponse(t_Event map[string]interface{}, rule types.Tp_mockRule) *types.TEngineResponse {
+
+	tenantId := fmt.Sprintf("%v", t_Event["tenantId"])
+
+	riskStatus := rule.RiskStatus
+	if riskStatus == "" {
+		riskStatus = "no-risk"
+		if rule.Alert == 1 {
+			riskStatus = "review"
+		}
+	}
+
+	account := types.TEntity{
+		TenantId:     tenantId,
+		EntityType:   "ACCOUNT",
+		EntityId:     mockEntityId(t_Event, "account"),
+		OverallScore: &types.TOverallScore{},
+		Models:       []types.TModel{},
+		OutputTags:   []types.TTag{},
+		RiskStatus:   riskStatus,
+		ConfigGroups: []types.TConfigGroup{},
+	}
+
+	if rule.Score > 0 || len(rule.TriggeredRules) > 0 {
+		score := rule.Score
+		one := 1.0
+
+		account.OverallScore.OverallScore = &score
+		account.Models = append(account.Models, types.TModel{ModelId: "businessrules", Score: &score, Tags: []types.TTag{}})
+
+		group := types.TConfigGroup{
+			Type:                    "global",
+			TriggeredRules:          append([]string{}, rule.TriggeredRules...),
+			RulesContributingEvents: map[string]interface{}{},
+		}
+		for _, ruleId := range rule.TriggeredRules {
+			group.Aggregators = append(group.Aggregators, types.TAggregator{
+				AggregatorId:   "Rule: " + ruleId,
+				AggregateScore: &one,
+				MatchedBound:   &one,
+				Alert:          rule.Alert == 1,
+				OutputTags:     []types.TTag{},
+				SuppressedTags: []types.TTag{},
+				Scores:         &types.TAggregatorScores{Rules: []types.TRuleScore{{RuleId: ruleId, Score: &one}}},
+			})
+		}
+		account.ConfigGroups = append(account.ConfigGroups, group)
+	}
+
+	counterparty := types.TEntity{
+		TenantId:     tenantId,
+		EntityType:   "COUNTERPARTY",
+		EntityId:     mockEntityId(t_Event, "counterparty"),
+		OverallScore: &types.TOverallScore{},
+		RiskStatus:   "no-risk",
+	}
+
+	return &types.TEngineResponse{
+		Entities:         []types.TEntity{account, counterparty},
+		JsonVersion:      4,
+		OriginatingEvent: t_Event,
+		OutputTime:       time.Now().UTC().Format(time.RFC3339),
+		ProcessorId:      "mock-engine",
+	}
+}
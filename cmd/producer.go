@@ -50,6 +50,55 @@
 *					: 3 Nov			- Adding the generate date/time control via updateActionDates, controlling if a supplied date and date/time is used or
 *									- if we generate based on system time. added to both addPayee* and payment*
 *
+*					: 19 Oct 2026	- Tenants can now carry multiple branch ranges, duplicate tenant entries in the seed file are merged at load time
+*					:				- so that branch id's are drawn across all of a bank's ranges. Added SpecialBranches (universal branch codes)
+*					:				- per tenant, used specialBranchRate % of the time.
+*					:				- RPP payloads are now shaped per localInstrument, PBPX carries the creditor proxy only, PBAC the account
+*					:				- details, RTP-* is preceded by a requestToPay event from the creditor's bank (see rtp.go)
+*					:				- RTP two phase flow, after rtpAcceptDelay the debtor's bank accepts, rejects or lets the request
+*					:				- expire (rtpAcceptRate/rtpRejectRate), only accepted requests are followed by the payment pair.
+*					:				- Accounts/tenants now come via a account provider, either the seed file or a local SQLite database
+*					:				- (accountSource), loaded via "fs_producer loaddb <env>", see provider.go
+*					:				- The per transaction processing moved out of runLoader into processTransaction, which is now run by a
+*					:				- pool of workers (workers), see worker.go. Event order within a transaction is unchanged.
+*					:				- Open-loop rate control, rateProfile constant/ramp/step/spike at targetEPS, see scheduler.go
+*					:				- httpCALL retries transient failures (maxRetries) with exponential backoff and jitter, see retry.go
+*					:				- continueOnError, failed events are written to deadLetter_path and the run carries on, the directory
+*					:				- can be re-posted via "fs_producer repost <env>", see deadletter.go
+*					:				- Graceful shutdown on SIGINT/SIGTERM, in-flight transactions complete (shutdownTimeout), summary
+*					:				- and final Prometheus push, exit code 128 + signal, see shutdown.go
+*					:				- Soak mode, run for soakDuration or until stopped, with progress checkpoints (progressInterval)
+*					:				- and optional pprof/expvar (pprofAddress), see soak.go. Idle connections kept per worker.
+*					:				- HDR latency histograms per eventType/direction/tenant, percentiles at the end of the run and
+*					:				- in results_file, open-loop also corrected for coordinated omission, see latency.go
+*					:				- API call phase timings (dns, connect, tls, server, ttfb, body) and connection reuse via httptrace,
+*					:				- in the debug log, -out.json files and Prometheus, see trace.go
+*					:				- HTTP client timeouts (default 30s per request), pooling, keep-alive, HTTP/2 and gzip request
+*					:				- compression configurable, see transport.go
+*					:				- mTLS, separate CA bundle (ca_file), server verification on by default (insecureSkipVerify opt-out),
+*					:				- serverName, TLS min version/ciphers, PKCS#12 client identity and cert expiry check, see tlsconfig.go
+*					:				- Gateway authentication, bearer, API key, HMAC or OAuth2 client credentials (authType), with a
+*					:				- local token endpoint stub, "fs_producer tokenstub <env>", see auth.go
+*					:				- Events are posted via a sink, the FS API (default), Kafka (topic per eventType, keyed by
+*					:				- transactionId), a JSONL file, stdout or in memory, see sink.go/sink_kafka.go
+*					:				- Local mock engine, "fs_producer mock-engine <env>", rule based scores/alerts with latency and
+*					:				- error injection, for offline development, see mockengine.go
+*					:				- Record/replay cassettes (cassette record/replay), responses matched on scenario step + eventType,
+*					:				- see cassette.go
+*					:				- engineResponses parsed into a typed model (types/engineresponse.go), RiskScoreExtract replaced by
+*					:				- the outcome summary (score, riskStatus, triggered/alerted rules per entity), see engineresponse.go
+*					:				- Rule hit matrix per transaction/scenario file, CSV and Markdown (ruleMatrix_file), see rulematrix.go
+*					:				- Outcomes per scenario step in results_file and the -out.json files, compared between runs via
+*					:				- "fs_producer diff <env> <run A> <run B>", see diff.go
+*					:				- Golden baselines of the scenario outcomes (golden), re-blessed via "fs_producer <env> --update-golden",
+*					:				- see golden.go
+*					:				- Self-contained HTML run report (report_file), config (secrets redacted), timeline, score
+*					:				- distribution, latencies, rule hit matrix, failures and engine versions, see report.go
+*					:				- Per-run output directories (runs_path) with a manifest, config, seed/input hashes, version,
+*					:				- host and start/end, "fs_producer runs <env> list|inspect|prune", see runs.go
+*					:				- Consolidated JSONL results file (resultsLog_file), a line per event, optionally gzip'ed and
+*					:				- rotated by size, instead of json_to_file/engineResponse_to_file's files, see resultslog.go
+*
 *
 *	By				: George Leonard (georgelza@gmail.com)
 *
@@ -73,6 +122,7 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/brianvoe/gofakeit"
@@ -87,9 +137,6 @@ import (
 
 	// My Types/Structs/functions
 	"cmd/types"
-
-	"crypto/tls"
-	"crypto/x509"
 	// Filter JSON array
 )
 
@@ -99,6 +146,10 @@ type Metrics struct {
 	err_pmnt_processed     *prometheus.CounterVec   // transactions/events ending in neither http 200 or 204
 	api_addpayee_duration  *prometheus.HistogramVec // API Call time per event
 	err_addpayee_processed *prometheus.CounterVec   // transactions/events ending in neither http 200 or 204
+	api_retries            *prometheus.CounterVec   // API call retries, by reason (connection, timeout, 429, 5xx)
+	api_phase_duration     *prometheus.HistogramVec // API call time per phase (dns, connect, tls, server, ttfb, bodyRead, total)
+	api_connections        *prometheus.CounterVec   // API calls on a new vs reused connection
+	engine_rule_hits       *prometheus.CounterVec   // rules triggered per engineResponse, see engineresponse.go
 }
 
 var (
@@ -176,9 +227,32 @@ func NewMetrics(reg prometheus.Registerer) *Metrics {
 			Name: "fs_err_addpayee_processed_total",
 			Help: "The number of err transactions processed for the FS.",
 		}, []string{"hostname", "msg_type", "service", "participant", "direction"}),
+
+		api_retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "fs_api_retries_total",
+			Help: "The number of FS API calls retried, by reason.",
+		}, []string{"hostname", "reason"}),
+
+		api_phase_duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name: "fs_api_phase_seconds",
+			Help: "Duration of the FS API request phases in seconds, see trace.go",
+			// 0.25ms -> ~8s
+			Buckets: prometheus.ExponentialBuckets(0.00025, 2, 16),
+		}, []string{"hostname", "phase"}),
+
+		api_connections: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "fs_api_connections_total",
+			Help: "The number of FS API calls on a new or reused connection.",
+		}, []string{"hostname", "reused"}),
+
+		engine_rule_hits: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "fs_engine_rule_hits_total",
+			Help: "The number of times a rule was triggered, and if it alerted, as per the engineResponses.",
+		}, []string{"hostname", "msg_type", "rule", "alert"}),
 	}
 
-	reg.MustRegister(m.api_pmnt_info, m.api_pmnt_duration, m.err_pmnt_processed, m.api_addpayee_duration, m.err_addpayee_processed)
+	reg.MustRegister(m.api_pmnt_info, m.api_pmnt_duration, m.err_pmnt_processed, m.api_addpayee_duration, m.err_addpayee_processed, m.api_retries,
+		m.api_phase_duration, m.api_connections, m.engine_rule_hits)
 
 	return m
 }
@@ -222,6 +296,20 @@ func loadConfig(params ...string) types.Tp_general {
 		vGeneral.Cert_file = fmt.Sprintf("%s%s%s%s%s", vGeneral.CurrentPath, pathSep, vGeneral.Cert_dir, pathSep, vGeneral.Cert_file)
 		vGeneral.Cert_key = fmt.Sprintf("%s%s%s%s%s", vGeneral.CurrentPath, pathSep, vGeneral.Cert_dir, pathSep, vGeneral.Cert_key)
 
+		if vGeneral.Ca_file != "" {
+			vGeneral.Ca_file = fmt.Sprintf("%s%s%s%s%s", vGeneral.CurrentPath, pathSep, vGeneral.Cert_dir, pathSep, vGeneral.Ca_file)
+
+		}
+		if vGeneral.MockEngineCert_file != "" {
+			vGeneral.MockEngineCert_file = fmt.Sprintf("%s%s%s%s%s", vGeneral.CurrentPath, pathSep, vGeneral.Cert_dir, pathSep, vGeneral.MockEngineCert_file)
+			vGeneral.MockEngineKey_file = fmt.Sprintf("%s%s%s%s%s", vGeneral.CurrentPath, pathSep, vGeneral.Cert_dir, pathSep, vGeneral.MockEngineKey_file)
+
+		}
+		if vGeneral.Pkcs12_file != "" {
+			vGeneral.Pkcs12_file = fmt.Sprintf("%s%s%s%s%s", vGeneral.CurrentPath, pathSep, vGeneral.Cert_dir, pathSep, vGeneral.Pkcs12_file)
+
+		}
+
 		vGeneral.Output_path = fmt.Sprintf("%s%s%s", vGeneral.CurrentPath, pathSep, vGeneral.Output_path)
 
 		if vGeneral.Json_from_file == 1 {
@@ -234,6 +322,61 @@ func loadConfig(params ...string) types.Tp_general {
 
 		vGeneral.SeedFile = fmt.Sprintf("%s%s%s", vGeneral.CurrentPath, pathSep, vGeneral.SeedFile)
 
+		if vGeneral.Results_file != "" {
+			vGeneral.Results_file = fmt.Sprintf("%s%s%s", vGeneral.CurrentPath, pathSep, vGeneral.Results_file)
+
+		}
+
+		if vGeneral.Sink_file != "" {
+			vGeneral.Sink_file = fmt.Sprintf("%s%s%s", vGeneral.CurrentPath, pathSep, vGeneral.Sink_file)
+
+		}
+
+		if vGeneral.Golden_file != "" {
+			vGeneral.Golden_file = fmt.Sprintf("%s%s%s", vGeneral.CurrentPath, pathSep, vGeneral.Golden_file)
+
+		}
+
+		if vGeneral.RuleMatrix_file != "" {
+			vGeneral.RuleMatrix_file = fmt.Sprintf("%s%s%s", vGeneral.CurrentPath, pathSep, vGeneral.RuleMatrix_file)
+
+		}
+
+		if vGeneral.Runs_path != "" {
+			vGeneral.Runs_path = fmt.Sprintf("%s%s%s", vGeneral.CurrentPath, pathSep, vGeneral.Runs_path)
+
+		}
+
+		if vGeneral.ResultsLog_file != "" {
+			vGeneral.ResultsLog_file = fmt.Sprintf("%s%s%s", vGeneral.CurrentPath, pathSep, vGeneral.ResultsLog_file)
+
+		}
+
+		if vGeneral.Report_file != "" {
+			vGeneral.Report_file = fmt.Sprintf("%s%s%s", vGeneral.CurrentPath, pathSep, vGeneral.Report_file)
+
+		}
+
+		if vGeneral.Cassette_file != "" {
+			vGeneral.Cassette_file = fmt.Sprintf("%s%s%s", vGeneral.CurrentPath, pathSep, vGeneral.Cassette_file)
+
+		}
+
+		if vGeneral.DeadLetter_path != "" {
+			vGeneral.DeadLetter_path = fmt.Sprintf("%s%s%s", vGeneral.CurrentPath, pathSep, vGeneral.DeadLetter_path)
+
+		}
+
+		if vGeneral.AccountDB != "" {
+			vGeneral.AccountDB = fmt.Sprintf("%s%s%s", vGeneral.CurrentPath, pathSep, vGeneral.AccountDB)
+
+		}
+
+	}
+
+	if err = checkSchedule(); err != nil {
+		grpcLog.Fatalln("Rate profile error: ", err)
+
 	}
 
 	if vGeneral.EchoConfig == 1 {
@@ -262,6 +405,18 @@ func loadSeed(fileName string) types.TPSeed {
 
 	}
 
+	// Some banks are listed multiple times, once per branch range, collapse them into a single tenant with a list of ranges.
+	vSeed.Tenants.Rt, err = mergeTenants(vSeed.Tenants.Rt)
+	if err != nil {
+		grpcLog.Fatalln("Error in Seed File: ", err)
+
+	}
+	vSeed.Tenants.Nrt, err = mergeTenants(vSeed.Tenants.Nrt)
+	if err != nil {
+		grpcLog.Fatalln("Error in Seed File: ", err)
+
+	}
+
 	v, err := json.Marshal(vSeed)
 	if err != nil {
 		grpcLog.Fatalln("Marchalling error: ", err)
@@ -294,8 +449,13 @@ func printConfig(vGeneral types.Tp_general) {
 	grpcLog.Info("* Debug Level is\t\t", vGeneral.Debuglevel)
 	grpcLog.Info("*")
 	grpcLog.Info("* Sleep Duration is\t\t", vGeneral.Sleep)
+	grpcLog.Info("* Workers is\t\t\t", vGeneral.Workers)
+	grpcLog.Info("* Rate Profile is\t\t", vGeneral.RateProfile)
+	grpcLog.Info("* Target Events/Second is\t", vGeneral.TargetEPS)
 	grpcLog.Info("* Test Batch Size is\t\t", vGeneral.Testsize)
 	grpcLog.Info("* Seed File is\t\t\t", vGeneral.SeedFile)
+	grpcLog.Info("* Account Source is\t\t", vGeneral.AccountSource)
+	grpcLog.Info("* Account DB is\t\t", vGeneral.AccountDB)
 	grpcLog.Info("* Echo Seed is\t\t", vGeneral.EchoSeed)
 	grpcLog.Info("* Echo JSON is\t\t", vGeneral.Echojson)
 	grpcLog.Info("*")
@@ -303,8 +463,35 @@ func printConfig(vGeneral types.Tp_general) {
 	grpcLog.Info("* Current Path is \t\t", vGeneral.CurrentPath)
 	grpcLog.Info("* Cert file is\t\t", vGeneral.Cert_file)
 	grpcLog.Info("* Cert key is\t\t\t", vGeneral.Cert_key)
+	grpcLog.Info("* PKCS#12 file is\t\t", vGeneral.Pkcs12_file)
+	grpcLog.Info("* CA file is\t\t\t", vGeneral.Ca_file)
+	grpcLog.Info("* Insecure Skip Verify is\t", vGeneral.InsecureSkipVerify)
+	grpcLog.Info("* Server Name is\t\t", vGeneral.ServerName)
+	grpcLog.Info("* TLS Min Version is\t\t", vGeneral.TLSMinVersion)
+	grpcLog.Info("* Auth Type is\t\t", vGeneral.AuthType)
+	grpcLog.Info("* OAuth Token URL is\t\t", vGeneral.OAuthTokenURL)
 
 	grpcLog.Info("* HTTP JSON POST URL is\t", vGeneral.Httpposturl)
+	grpcLog.Info("* Sink is\t\t\t", vGeneral.Sink)
+	grpcLog.Info("* Sink file is\t\t", vGeneral.Sink_file)
+	grpcLog.Info("* Kafka Brokers is\t\t", vGeneral.KafkaBrokers)
+	grpcLog.Info("* Cassette is\t\t\t", vGeneral.Cassette)
+	grpcLog.Info("* Cassette file is\t\t", vGeneral.Cassette_file)
+	grpcLog.Info("* Mock Engine Address is\t", vGeneral.MockEngineAddress)
+	grpcLog.Info("* Mock Engine TLS is\t\t", vGeneral.MockEngineTls)
+	grpcLog.Info("* Mock Rules is\t\t", len(vGeneral.MockRules))
+	grpcLog.Info("* Max Retries is\t\t", vGeneral.MaxRetries)
+	grpcLog.Info("* HTTP Timeout is\t\t", vGeneral.HttpTimeout, " ms")
+	grpcLog.Info("* Max Idle Conns/Host is\t", vGeneral.MaxIdleConnsPerHost)
+	grpcLog.Info("* HTTP/2 is\t\t\t", vGeneral.Http2)
+	grpcLog.Info("* Gzip Requests is\t\t", vGeneral.GzipRequests)
+	grpcLog.Info("* Continue On Error is\t", vGeneral.ContinueOnError)
+	grpcLog.Info("* Dead-letter path is\t\t", vGeneral.DeadLetter_path)
+	grpcLog.Info("* Shutdown Timeout is\t\t", vGeneral.ShutdownTimeout, " Sec")
+	grpcLog.Info("* Soak is\t\t\t", vGeneral.Soak)
+	grpcLog.Info("* Soak Duration is\t\t", vGeneral.SoakDuration)
+	grpcLog.Info("* Progress Interval is\t", vGeneral.ProgressInterval, " Sec")
+	grpcLog.Info("* pprof Address is\t\t", vGeneral.PprofAddress)
 
 	grpcLog.Info("* Read JSON from file is\t", vGeneral.Json_from_file) // if 0 then we create fake data else
 	grpcLog.Info("* Input path is\t\t", vGeneral.Input_path)            // if 1 then read files from input_path
@@ -313,9 +500,26 @@ func printConfig(vGeneral types.Tp_general) {
 
 	grpcLog.Info("* Output JSON to file is\t", vGeneral.Json_to_file)
 	grpcLog.Info("* Output path is\t\t", vGeneral.Output_path)
+	grpcLog.Info("* Runs path is\t\t", vGeneral.Runs_path)
+	grpcLog.Info("* Runs Keep is\t\t", vGeneral.RunsKeep)
+	grpcLog.Info("* Results file is\t\t", vGeneral.Results_file)
+	grpcLog.Info("* Rule Matrix file is\t", vGeneral.RuleMatrix_file)
+	grpcLog.Info("* Report file is\t\t", vGeneral.Report_file)
+	grpcLog.Info("* Results Log file is\t", vGeneral.ResultsLog_file)
+	grpcLog.Info("* Results Log Gzip is\t", vGeneral.ResultsLogGzip)
+	grpcLog.Info("* Results Log Max Size is\t", vGeneral.ResultsLogMaxSize, "MB")
+	grpcLog.Info("* Diff Score Threshold is\t", vGeneral.DiffScoreThreshold)
+	grpcLog.Info("* Diff Max Changes is\t", vGeneral.DiffMaxChanges)
+	grpcLog.Info("* Diff Latency Threshold is\t", vGeneral.DiffLatencyThreshold)
+	grpcLog.Info("* Golden is\t\t\t", vGeneral.Golden)
+	grpcLog.Info("* Golden file is\t\t", goldenFile())
+	grpcLog.Info("* Update Golden is\t\t", vUpdateGolden)
 
 	grpcLog.Info("* MinTransactionValue is\tR ", vGeneral.MinTransactionValue)
 	grpcLog.Info("* MaxTransactionValue is\tR ", vGeneral.MaxTransactionValue)
+	grpcLog.Info("* Special Branch Rate is\t", vGeneral.SpecialBranchRate, "%")
+	grpcLog.Info("* RTP Accept Delay is\t\t", vGeneral.RtpAcceptDelay, " ms")
+	grpcLog.Info("* RTP Accept/Reject Rate is\t", vGeneral.RtpAcceptRate, "% / ", vGeneral.RtpRejectRate, "%")
 
 	grpcLog.Info("*")
 	grpcLog.Info("*******************************")
@@ -356,6 +560,86 @@ func findTenant(tenants []types.TTenant, filter string) (ret types.TTenant, err
 	return ret, err
 }
 
+// Collapse duplicate tenant entries (same TenantId, different branch range) into a single tenant. Every entry's
+// BranchRangeStart/BranchRangeEnd pair (or it's own BranchRanges list if supplied) is appended to BranchRanges.
+// A inverted range (end before start) is rejected, fakeBranchId can't draw from it.
+func mergeTenants(tenants []types.TTenant) ([]types.TTenant, error) {
+
+	var merged []types.TTenant
+	index := make(map[string]int)
+
+	for _, item := range tenants {
+
+		ranges := item.BranchRanges
+		if len(ranges) == 0 {
+			ranges = []types.TBranchRange{{Start: item.BranchRangeStart, End: item.BranchRangeEnd}}
+		}
+		for _, r := range ranges {
+			if r.End < r.Start {
+				return nil, fmt.Errorf("tenant %s branch range %d - %d, end before start", item.TenantId, r.Start, r.End)
+			}
+		}
+
+		i, ok := index[item.TenantId]
+		if !ok {
+			item.BranchRanges = ranges
+			index[item.TenantId] = len(merged)
+			merged = append(merged, item)
+			continue
+		}
+
+		tenant := &merged[i]
+		tenant.BranchRanges = append(tenant.BranchRanges, ranges...)
+		if tenant.Bicfi == "" {
+			tenant.Bicfi = item.Bicfi
+		}
+		for _, code := range item.SpecialBranches {
+			found := false
+			for _, existing := range tenant.SpecialBranches {
+				if existing == code {
+					found = true
+					break
+				}
+			}
+			if !found {
+				tenant.SpecialBranches = append(tenant.SpecialBranches, code)
+			}
+		}
+	}
+
+	return merged, nil
+}
+
+// Pick a branch id for the tenant, either one of it's special (universal) branch codes, specialBranchRate % of the time,
+// or a value drawn across all of it's branch ranges, weighted by the size of each range.
+func fakeBranchId(tenant types.TTenant) string {
+
+	if len(tenant.SpecialBranches) > 0 && gofakeit.Number(1, 100) <= vGeneral.SpecialBranchRate {
+		return tenant.SpecialBranches[gofakeit.Number(0, len(tenant.SpecialBranches)-1)]
+	}
+
+	ranges := tenant.BranchRanges
+	if len(ranges) == 0 {
+		ranges = []types.TBranchRange{{Start: tenant.BranchRangeStart, End: tenant.BranchRangeEnd}}
+	}
+
+	total := 0
+	for _, r := range ranges {
+		total += r.End - r.Start + 1
+	}
+
+	n := gofakeit.Number(0, total-1)
+	for _, r := range ranges {
+		size := r.End - r.Start + 1
+		if n < size {
+			return strconv.Itoa(r.Start + n)
+		}
+		n -= size
+	}
+
+	return strconv.Itoa(ranges[0].Start)
+}
+
 // - FAKE Data generation
 // - Build a fin transaction.
 // 1. are we doing "hist" or rpp, if hist then we also use sourcesystem to control which source system is used.
@@ -394,8 +678,6 @@ func constructFakeFinTransaction() (t_OutboundPayment map[string]interface{}, t_
 	// https://github.com/brianvoe/gofakeit
 	// https://pkg.go.dev/github.com/brianvoe/gofakeit
 
-	gofakeit.Seed(0)
-
 	nAmount := gofakeit.Price(vGeneral.MinTransactionValue, vGeneral.MaxTransactionValue)
 	t_amount := &types.TAmount{
 		BaseCurrency: "zar",
@@ -404,20 +686,34 @@ func constructFakeFinTransaction() (t_OutboundPayment map[string]interface{}, t_
 		Value:        nAmount,
 	}
 
-	// Determine how many accounts we have in seed file, it's all about them...
+	// It's all about the accounts, pick the debtor and creditor via the account provider (seed file or sqlite)
 	// and build the 2 structures from that viewpoint
-	accountsCount := len(varSeed.Accounts.Good) - 1
-	nDebtorAccount := gofakeit.Number(0, accountsCount)
-	nCreditorAccount := gofakeit.Number(0, accountsCount)
+	filter := configuredAccountFilter()
+	if vGeneral.AccountStream == 1 {
+		jDebtorAccount, err = nextStreamedAccount(filter)
+
+	} else {
+		jDebtorAccount, err = vAccounts.RandomAccount(filter)
+
+	}
+	if err != nil {
+		grpcLog.Errorln("Debtor account error: ", err)
+		return nil, nil, err
 
-	// check to make sure the 2 are not the same, if they are, redo...
-	if nDebtorAccount == nCreditorAccount {
-		nCreditorAccount = gofakeit.Number(0, accountsCount)
 	}
 
-	// find the debtor and creditor record to use
-	jDebtorAccount = varSeed.Accounts.Good[nDebtorAccount]
-	jCreditorAccount = varSeed.Accounts.Good[nCreditorAccount]
+	// The creditor is not limited to the configured tenant/type, only status
+	jCreditorAccount, err = vAccounts.RandomAccount(AccountFilter{Status: filter.Status})
+	if err != nil {
+		grpcLog.Errorln("Creditor account error: ", err)
+		return nil, nil, err
+
+	}
+
+	// check to make sure the 2 are not the same, if they are, redo...
+	if jDebtorAccount.Id == jCreditorAccount.Id {
+		jCreditorAccount, _ = vAccounts.RandomAccount(AccountFilter{Status: filter.Status})
+	}
 
 	if vGeneral.Datamode == "hist" {
 
@@ -450,16 +746,16 @@ func constructFakeFinTransaction() (t_OutboundPayment map[string]interface{}, t_
 			msgType = "RTCCT"
 
 		}
-		jDebtorBank, _ = findTenant(varSeed.Tenants.Nrt, jDebtorAccount.TenantId)
-		jCreditorBank, _ = findTenant(varSeed.Tenants.Nrt, jCreditorAccount.TenantId)
+		jDebtorBank, _ = vAccounts.FindTenant(vGeneral.Datamode, jDebtorAccount.TenantId)
+		jCreditorBank, _ = vAccounts.FindTenant(vGeneral.Datamode, jCreditorAccount.TenantId)
 
 		localInstrumentCount := len(varSeed.LocalInstrument.HIST) - 1
 		nlocalInstrumentCount := gofakeit.Number(0, localInstrumentCount)
 		localInstrument = varSeed.LocalInstrument.HIST[nlocalInstrumentCount].Name
 
 	} else { // RPP
-		jDebtorBank, _ = findTenant(varSeed.Tenants.Rt, jDebtorAccount.TenantId)
-		jCreditorBank, _ = findTenant(varSeed.Tenants.Rt, jCreditorAccount.TenantId)
+		jDebtorBank, _ = vAccounts.FindTenant(vGeneral.Datamode, jDebtorAccount.TenantId)
+		jCreditorBank, _ = vAccounts.FindTenant(vGeneral.Datamode, jCreditorAccount.TenantId)
 
 		localInstrumentCount := len(varSeed.LocalInstrument.RPP) - 1
 		nlocalInstrumentCount := gofakeit.Number(0, localInstrumentCount)
@@ -468,8 +764,8 @@ func constructFakeFinTransaction() (t_OutboundPayment map[string]interface{}, t_
 	}
 
 	// find FIB Id for the debtor and creditor bank
-	DebtorFIBranchId = strconv.Itoa(gofakeit.Number(jDebtorBank.BranchRangeStart, jDebtorBank.BranchRangeEnd))
-	CreditorFIBranchId = strconv.Itoa(gofakeit.Number(jCreditorBank.BranchRangeStart, jCreditorBank.BranchRangeEnd))
+	DebtorFIBranchId = fakeBranchId(jDebtorBank)
+	CreditorFIBranchId = fakeBranchId(jCreditorBank)
 
 	txnId = uuid.New().String()
 	eventTime = time.Now().Format("2006-01-02T15:04:05")
@@ -720,11 +1016,54 @@ func constructFakeFinTransaction() (t_OutboundPayment map[string]interface{}, t_
 			"ultimateCounterpartyName":          jDebtorAccount.Name,
 			"unstructuredRemittanceInformation": paymentClearingSystemReference,
 		}
+
+		// PBPX/PBAC/RTP-* differ in how the creditor is identified, reshape the 2 payloads accordingly.
+		shapeRPPInstrument(localInstrument, t_OutboundPayment, t_InboundPayment, jCreditorAccount)
 	}
 
 	return t_OutboundPayment, t_InboundPayment, nil
 }
 
+// RPP instrument specific payload shaping.
+//
+// PBPX / RTP-PBPX	: pay by proxy, the creditor is identified by it's proxy id/type/domain only, no creditor account number.
+// PBAC / RTP-PBAC	: pay by account, the creditor is identified by account details, no creditor proxy.
+//
+// The RTP-* request-to-pay event itself is built by constructRequestToPay().
+//
+// Outbound (debtor bank) => creditor is the counterparty
+// Inbound (creditor bank) => creditor is the account
+func shapeRPPInstrument(localInstrument string, t_OutboundPayment map[string]interface{}, t_InboundPayment map[string]interface{}, jCreditorAccount types.TAccount) {
+
+	switch strings.TrimPrefix(localInstrument, "RTP-") {
+	case "PBPX":
+		delete(t_OutboundPayment, "counterpartyId")
+		delete(t_OutboundPayment, "counterpartyNumber")
+		delete(t_OutboundPayment, "counterpartyIdCode")
+		t_OutboundPayment["counterpartyProxyId"] = jCreditorAccount.ProxyId
+		t_OutboundPayment["counterpartyProxyType"] = jCreditorAccount.ProxyType
+		t_OutboundPayment["counterpartyDomain"] = jCreditorAccount.ProxyDomain
+
+		delete(t_InboundPayment, "accountId")
+		delete(t_InboundPayment, "accountNumber")
+		delete(t_InboundPayment, "accountIdCode")
+		delete(t_InboundPayment, "accountCustomerId")
+		t_InboundPayment["accountProxyId"] = jCreditorAccount.ProxyId
+		t_InboundPayment["accountProxyType"] = jCreditorAccount.ProxyType
+		t_InboundPayment["accountDomain"] = jCreditorAccount.ProxyDomain
+
+	case "PBAC":
+		delete(t_OutboundPayment, "counterpartyProxyId")
+		delete(t_OutboundPayment, "counterpartyProxyType")
+		delete(t_OutboundPayment, "counterpartyDomain")
+
+		delete(t_InboundPayment, "accountProxyId")
+		delete(t_InboundPayment, "accountProxyType")
+		delete(t_InboundPayment, "accountDomain")
+
+	}
+}
+
 func contructFinTransactionFromFile(varRec string) (t_OutboundPayment map[string]interface{}, t_InboundPayment map[string]interface{}, err error) {
 
 	var objs interface{}
@@ -911,27 +1250,15 @@ func ConstructHTTPClient() (*http.Client, error) {
 	// Create client with Cert once
 	// https://stackoverflow.com/questions/38822764/how-to-send-a-https-request-with-a-certificate-golang
 
-	caCert, err := os.ReadFile(vGeneral.Cert_file)
+	// Client identity, CA bundle, verification, SNI and TLS version/ciphers, see tlsconfig.go
+	tlsConfig, err := buildTLSConfig()
 	if err != nil {
-
-		x := fmt.Sprintf("Problem reading: %s Error: %s", vGeneral.Cert_file, err)
-		err = errors.New(x)
-		grpcLog.Errorln(err)
 		return nil, err
 
 	}
 
-	caCertPool := x509.NewCertPool()
-	caCertPool.AppendCertsFromPEM(caCert)
-
-	cert, err := tls.LoadX509KeyPair(vGeneral.Cert_file, vGeneral.Cert_key)
-	if err != nil {
-		x := fmt.Sprintf("Problem with LoadX509KeyPair: %s Error: %s", vGeneral.Cert_key, err)
-		err = errors.New(x)
-		grpcLog.Errorln(err)
-		return nil, err
-
-	}
+	// Timeouts, pooling and HTTP/2 as per config, see transport.go
+	transport := newTransport(tlsConfig)
 
 	if vGeneral.ProxyURL_enabled == 1 {
 		grpcLog.Info("* HTTP Client With Proxy Server defined")
@@ -944,108 +1271,129 @@ func ConstructHTTPClient() (*http.Client, error) {
 			return nil, err
 
 		}
-
-		client = &http.Client{
-			Transport: &http.Transport{
-				TLSClientConfig: &tls.Config{
-					RootCAs:            caCertPool,
-					InsecureSkipVerify: true, // Self Signed cert
-					Certificates:       []tls.Certificate{cert},
-				},
-				Proxy: http.ProxyURL(proxyURL),
-			},
-		}
+		transport.Proxy = http.ProxyURL(proxyURL)
 
 	} else {
 		grpcLog.Info("* HTTP Client Without Proxy Server defined")
 
-		client = &http.Client{
-			Transport: &http.Transport{
-				TLSClientConfig: &tls.Config{
-					RootCAs:            caCertPool,
-					InsecureSkipVerify: true, // Self Signed cert
-					Certificates:       []tls.Certificate{cert},
-				},
-			},
-		}
 	}
 
-	return client, nil
-
-}
-
-func httpCALL(Bytes []byte, url string, client *http.Client) (Response *http.Response, err error) {
-
-	// https://golangtutorial.dev/tips/http-post-json-go/
-	Request, err := http.NewRequest("POST", url, bytes.NewBuffer(Bytes))
-	if err != nil {
-		x := fmt.Sprintf("http.NewRequest error: %s", err)
-		err = errors.New(x)
-		grpcLog.Errorln(err)
-		return nil, err
-
+	client = &http.Client{
+		Transport: transport,
+		Timeout:   clientTimeout(),
 	}
-	Request.Header.Set("Content-Type", "application/json; charset=UTF-8")
 
-	httpResponse, err := client.Do(Request)
+	// Bearer/API key/HMAC/OAuth2 on top of mTLS, see auth.go
+	vAuth, err = newAuthProvider(client)
 	if err != nil {
-		x := fmt.Sprintf("client.Do error: %s", err)
-		err = errors.New(x)
 		grpcLog.Errorln(err)
 		return nil, err
 
 	}
 
-	return httpResponse, nil
-}
-
-func RiskScoreExtract(t_Response map[string]interface{}) (riskScore float64, err error) {
+	return client, nil
 
-	// look at https://github.com/xing393939/jsonobject
+}
 
-	// Score Extraction
-	var vScore float64
-	vScore = 0.0
+// POST the event, transient failures (connection errors, timeouts, 429, 5xx) are retried up to maxRetries times with
+// backoff, see retry.go. We always post the same Bytes, so the eventId doesn't change between attempts.
+func httpCALL(Bytes []byte, url string, client *http.Client) (Response *http.Response, retries int, err error) {
 
-	// Access the nested array
-	entities, ok := t_Response["entities"].([]interface{})
-	if !ok {
-		err = errors.New("error accessing entities")
-		grpcLog.Errorln(err)
-		return 0, err
+	if vGeneral.GzipRequests == 1 {
+		if Bytes, err = gzipBody(Bytes); err != nil {
+			x := fmt.Sprintf("gzip error: %s", err)
+			err = errors.New(x)
+			grpcLog.Errorln(err)
+			return nil, 0, err
 
+		}
 	}
 
-	// entities are an array of json objects
-	for _, entity := range entities {
-		entityMap, ok := entity.(map[string]interface{})
-		if !ok {
-			err = errors.New("error accessing entity details")
+	var refreshed bool
+	for attempt := 0; ; attempt++ {
+
+		// https://golangtutorial.dev/tips/http-post-json-go/
+		Request, err := http.NewRequest("POST", url, bytes.NewBuffer(Bytes))
+		if err != nil {
+			x := fmt.Sprintf("http.NewRequest error: %s", err)
+			err = errors.New(x)
 			grpcLog.Errorln(err)
-			return 0, err
+			return nil, attempt, err
+
 		}
+		Request.Header.Set("Content-Type", "application/json; charset=UTF-8")
+		if vGeneral.GzipRequests == 1 {
+			Request.Header.Set("Content-Encoding", "gzip")
 
-		// Access the details map for each language
-		overallScores, ok := entityMap["overallScore"].(map[string]interface{})
-		if !ok {
-			err = errors.New("error accessing overall score map details")
-			grpcLog.Errorln(err)
 		}
 
-		for _, value := range overallScores {
-			if value != nil {
-				f, ok := value.(float64) // this won't panic because there's an ok
-				if ok {
-					if f > float64(vScore) {
-						vScore = f
-					}
-				}
+		// Gateway authentication, if configured, see auth.go
+		if vAuth != nil {
+			if err = vAuth.Apply(Request, Bytes); err != nil {
+				grpcLog.Errorln(err)
+				return nil, attempt, err
+
 			}
 		}
-	}
 
-	return vScore, nil
+		// Phase timings, see trace.go
+		Request = traceRequest(Request)
+
+		httpResponse, err := client.Do(Request)
+
+		// Stale token, refresh it once and re-post, this doesn't count as a retry
+		if err == nil && httpResponse.StatusCode == http.StatusUnauthorized && !refreshed {
+			if refresher, ok := vAuth.(tokenRefresher); ok {
+				io.Copy(io.Discard, httpResponse.Body)
+				httpResponse.Body.Close()
 
+				refresher.Invalidate()
+				refreshed = true
+				attempt--
+				continue
+
+			}
+		}
+
+		transient, reason := classifyFailure(httpResponse, err)
+		if !transient || attempt >= vGeneral.MaxRetries {
+			if err != nil {
+				x := fmt.Sprintf("client.Do error: %s", err)
+				err = errors.New(x)
+				grpcLog.Errorln(err)
+				return nil, attempt, err
+
+			}
+			atomic.AddInt64(&vStats.events, 1)
+			traceResponse(httpResponse)
+			return httpResponse, attempt, nil
+		}
+
+		wait := retryBackoff(attempt, httpResponse)
+		if httpResponse != nil {
+			io.Copy(io.Discard, httpResponse.Body)
+			httpResponse.Body.Close()
+		}
+
+		if vGeneral.Debuglevel > 1 {
+			grpcLog.Infoln("Retrying after                :", reason, "attempt", attempt+1, "in", wait.Seconds(), "Sec")
+
+		}
+
+		if vGeneral.Prometheus_enabled == 1 {
+			m.api_retries.With(prometheus.Labels{"hostname": vGeneral.Hostname, "reason": reason}).Inc()
+
+		}
+		atomic.AddInt64(&vStats.retries, 1)
+
+		// No retrying once a shutdown was requested, the event is dead-lettered and can be re-posted
+		if !sleepOrStop(wait) {
+			err = fmt.Errorf("shutdown requested, not retrying after %s", reason)
+			grpcLog.Errorln(err)
+			return nil, attempt, err
+
+		}
+	}
 }
 
 // Big worker... This si where everything happens.
@@ -1057,6 +1405,18 @@ func runLoader(arg string) {
 	// Lets get Seed Data from the specified seed file
 	varSeed = loadSeed(vGeneral.SeedFile)
 
+	// Seed the fake data generator once, workers share the (locked) global source.
+	gofakeit.Seed(0)
+
+	// Accounts and tenants, either from the seed file or the account database
+	var err error
+	vAccounts, err = newAccountProvider()
+	if err != nil {
+		grpcLog.Fatalln("Account provider error: ", err)
+
+	}
+	defer vAccounts.Close()
+
 	if vGeneral.Prometheus_enabled == 1 {
 		pusher = push.New(vGeneral.Prometheus_push_gateway, "pushgateway").Gatherer(reg)
 	}
@@ -1068,6 +1428,13 @@ func runLoader(arg string) {
 
 	}
 
+	// Where the events go, the FS API (default), Kafka, file, stdout or memory, see sink.go
+	vSink, err = newSink(client)
+	if err != nil {
+		grpcLog.Fatalln("Sink error: ", err)
+
+	}
+
 	if vGeneral.Debuglevel > 0 {
 		grpcLog.Info("**** LETS GO Processing ****")
 		grpcLog.Infoln("")
@@ -1078,7 +1445,7 @@ func runLoader(arg string) {
 	// Lets fecth the records that need to be pushed to the fs api end point
 	var todo_count = 0
 	var returnedRecs map[int]string
-	if vGeneral.Json_from_file == 0 { // Build Fake Record - atm we're generating the data, eventually we might fetch via SQL
+	if vGeneral.Json_from_file == 0 { // Build Fake Record - accounts via the account provider, seed file or sqlite
 
 		// As we're faking it:
 		todo_count = vGeneral.Testsize // this will be recplaced by the value of todo_count from above.
@@ -1161,268 +1528,517 @@ func runLoader(arg string) {
 	// this is to keep record of the total batch run time
 	vStart := time.Now()
 
-	for count := 0; count < todo_count; count++ {
+	// Per-run output directory and manifest, see runs.go
+	startRun(arg, vStart, returnedRecs)
 
-		reccount := fmt.Sprintf("%v", count+1)
+	if err = openResultsLog(); err != nil {
+		grpcLog.Fatalln("Results log error: ", err)
 
-		if vGeneral.Debuglevel > 0 {
-			grpcLog.Infoln("")
-			grpcLog.Infoln("Record                        :", reccount)
+	}
+
+	if vGeneral.DeadLetter_path != "" {
+		if err = os.MkdirAll(vGeneral.DeadLetter_path, 0755); err != nil {
+			grpcLog.Fatalln("Problem creating dead-letter directory: ", err)
 
 		}
+	}
 
-		// We're going to time every record and push that to prometheus
-		txnStart := time.Now()
+	// Soak mode, run for soakDuration/until stopped rather than todo_count transactions, see soak.go
+	todo_count = soakSetup(todo_count)
+	startPprof()
 
-		// We creating fake data so we will have 2 events to deal with
-		var t_InboundPayload map[string]interface{}
-		var t_OutboundPayload map[string]interface{}
-		var InboundBytes []byte
-		var OutboundBytes []byte
-		var jsonDataInboundResponsebody []byte
-		var jsonDataOutboundResponsebody []byte
-		var tInboundBody map[string]interface{}
-		var tOutboundBody map[string]interface{}
+	// Ctrl-C/SIGTERM, stop handing out transactions and let the in-flight ones complete, see shutdown.go
+	handleSignals()
 
-		// Build the entire JSON Payload document, either a fake record or from a input/scenario JSON file
-		if vGeneral.Json_from_file == 0 { // Build Fake Record
+	// Hand the records to the worker pool, each worker processes a transaction at a time, in the required event order.
+	// With a rateProfile configured the transactions are started on a open-loop schedule instead, see scheduler.go
+	vProgressDone := make(chan struct{})
+	go runProgress(vStart, vProgressDone)
 
-			// They are just to different to have kept in one function, so split them into 2 seperate specific use case functions.
-			// we use the same return structure as contructTransactionFromJSONFile()
-			t_OutboundPayload, t_InboundPayload, err = constructFakeFinTransaction()
-			if err != nil {
-				os.Exit(1)
+	vDrained := runWorkers(todo_count, func(job txnJob) {
+		if err := processTransaction(job, returnedRecs, vService); err != nil && vGeneral.ContinueOnError != 1 {
+			// As for a Ctrl-C, the in-flight transactions complete and the run's output is still written
+			requestStop(nil, err)
 
-			}
-		} else {
-			// We're reading data from files, so simply post data per file/payload
+		}
+	})
+	close(vProgressDone)
 
-			// returnedRecs is a map of file names, each filename is 2 JSON documents, each of which is a FS Payment (or addProxy) event,
-			// At this point we simply post the events onto the FS end point, and record the response.
+	// Transactions still in flight after shutdownTimeout may still write to the sink and results log, so these are
+	// only closed once all the workers are done.
+	if vDrained {
+		if err = vSink.Close(); err != nil {
+			grpcLog.Errorln("Sink close error: ", err)
 
-			filename := fmt.Sprintf("%s%s%s", vGeneral.Input_path, pathSep, returnedRecs[count])
+		}
+		closeResultsLog()
+
+	} else {
+		grpcLog.Warningln("Sink and results log not closed, transactions still in flight")
 
-			if vGeneral.Debuglevel > 2 {
-				grpcLog.Infoln("Source Event                  :", filename)
+	}
 
-			}
-			t_OutboundPayload, t_InboundPayload, err = contructFinTransactionFromFile(filename)
-			if err != nil {
-				os.Exit(1)
+	grpcLog.Infoln("")
+	grpcLog.Infoln("**** DONE Processing ****")
+	grpcLog.Infoln("")
+
+	vEnd := time.Now()
+	vElapse := vEnd.Sub(vStart)
+	grpcLog.Infoln("Start                         : ", vStart)
+	grpcLog.Infoln("End                           : ", vEnd)
+	grpcLog.Infoln("Elapsed Time (Seconds)        : ", vElapse.Seconds())
+	grpcLog.Infoln("Workers                       : ", vGeneral.Workers)
+	grpcLog.Infoln("Records Processed             : ", atomic.LoadInt64(&vStats.txns))
+	grpcLog.Infoln("Events Posted                 : ", atomic.LoadInt64(&vStats.events))
+	grpcLog.Infoln("Retries                       : ", atomic.LoadInt64(&vStats.retries))
+	grpcLog.Infoln("Events Failed                 : ", atomic.LoadInt64(&vStats.failed))
+	grpcLog.Infoln("Connections New/Reused        : ", atomic.LoadInt64(&vStats.connNew), "/", atomic.LoadInt64(&vStats.connReused))
+	grpcLog.Infoln(fmt.Sprintf("                              :  %.3f Txns/Second", float64(atomic.LoadInt64(&vStats.txns))/vElapse.Seconds()))
+	grpcLog.Infoln(fmt.Sprintf("                              :  %.3f Events/Second", float64(atomic.LoadInt64(&vStats.events))/vElapse.Seconds()))
+
+	if vGeneral.RateProfile != "" {
+		grpcLog.Infoln("Behind Schedule (Txns)        : ", atomic.LoadInt64(&vStats.behind))
+		grpcLog.Infoln("Max Schedule Lag (Seconds)    : ", time.Duration(atomic.LoadInt64(&vStats.maxLag)).Seconds())
+
+	}
+
+	//		grpcLog.Infoln(fmt.Sprintf("Transactions # / second       :  %.3f Txns/Second", float64(todo_count)/vElapse.Seconds()))
+	//		grpcLog.Infoln(fmt.Sprintf("Events # / second  (x2 Txns)  :  %.3f Events/Sec", float64(todo_count)/vElapse.Seconds()*2))
+
+	if stopping() {
+		grpcLog.Infoln("Interrupted by                : ", stopReason())
+
+	}
+
+	reportLatencies()
+	reportRuleMatrix()
+	vGoldenRegressions, vGoldenErr := checkGolden()
+	if vGoldenErr != nil {
+		grpcLog.Errorln(vGoldenErr)
+
+	}
+	reportRun(vStart, vEnd)
+	endRun(vStart, vEnd)
+
+	grpcLog.Infoln("")
+
+	// Final push, make sure the last transactions' metrics are not lost
+	if vGeneral.Prometheus_enabled == 1 {
+		if err := pusher.Add(); err != nil {
+			grpcLog.Errorln("Could not push to Pushgateway:", err)
 
-			}
 		}
+	}
 
-		if vGeneral.Debuglevel > 1 {
+	if stopping() {
+		grpcLog.Info("****** Interrupted        *****")
+		os.Exit(signalExitCode(vStopSignal))
 
-			// We can display the t_InboundPayload values here as we assigned the same values, inbound payment to be before outbound
-			// to both the inbound and outbound Payloads
-			grpcLog.Infoln("transactionId assigned        :", t_InboundPayload["transactionId"])
-			grpcLog.Infoln("eventTime assigned            :", t_InboundPayload["eventTime"])
-			grpcLog.Infoln("creationDate assigned         :", t_InboundPayload["creationDate"])
+	}
 
-			if t_InboundPayload["eventType"] == "paymentNRT" || t_InboundPayload["eventType"] == "paymentRT" {
-				// payment*
-				grpcLog.Infoln("requestExecutionDate assigned :", t_InboundPayload["requestExecutionDate"])
-				grpcLog.Infoln("settlementDate assigned       :", t_InboundPayload["settlementDate"])
+	if vGoldenRegressions > 0 {
+		grpcLog.Info("****** Golden Regressions *****")
+		os.Exit(1)
 
-				grpcLog.Infoln("")
-				grpcLog.Infoln("Inbound eventId assigned      :", t_InboundPayload["eventId"])
-				grpcLog.Infoln("Outbound eventId assigned     :", t_OutboundPayload["eventId"])
+	}
 
-			} else {
-				// addPayee*
-				grpcLog.Infoln("")
-				grpcLog.Infoln("Outbound                      :")
-				grpcLog.Infoln("eventId assigned              :", t_OutboundPayload["eventId"])
+	if vGoldenErr != nil {
+		grpcLog.Info("****** Golden Failed      *****")
+		os.Exit(1)
 
-				grpcLog.Infoln("")
-				grpcLog.Infoln("Inbound                       :")
-				grpcLog.Infoln("eventId assigned              :", t_InboundPayload["eventId"])
+	}
 
-			}
+} // runLoader()
+
+// Process a single transaction, build (fake or from file) the events, post them onto the API endpoint in the required
+// order and write the output files. Called by the workers, each transaction is handled by exactly one worker.
+// Returns the error that failed the transaction, if any.
+func processTransaction(job txnJob, returnedRecs map[int]string, vService string) error {
+
+	var err error
+
+	count := job.count
+	reccount := fmt.Sprintf("%v", count+1)
+
+	if vGeneral.Debuglevel > 0 {
+		grpcLog.Infoln("")
+		grpcLog.Infoln("Record                        :", reccount)
+
+		if vGeneral.RateProfile != "" {
+			grpcLog.Infoln("Schedule lag                  :", time.Since(job.scheduled).Seconds(), "Sec")
 
 		}
+	}
+
+	// We're going to time every record and push that to prometheus
+	txnStart := time.Now()
+
+	// How late we started against the schedule, used to correct the latencies for coordinated omission (open-loop)
+	vLag := txnStart.Sub(job.scheduled)
+
+	// We creating fake data so we will have 2 events to deal with
+	var t_InboundPayload map[string]interface{}
+	var t_OutboundPayload map[string]interface{}
+	var InboundBytes []byte
+	var OutboundBytes []byte
+	var jsonDataInboundResponsebody []byte
+	var jsonDataOutboundResponsebody []byte
+	var tInboundBody map[string]interface{}
+	var tOutboundBody map[string]interface{}
 
-		InboundBytes, err = json.Marshal(t_InboundPayload)
+	// Build the entire JSON Payload document, either a fake record or from a input/scenario JSON file
+	if vGeneral.Json_from_file == 0 { // Build Fake Record
+
+		// They are just to different to have kept in one function, so split them into 2 seperate specific use case functions.
+		// we use the same return structure as contructTransactionFromJSONFile()
+		t_OutboundPayload, t_InboundPayload, err = constructFakeFinTransaction()
 		if err != nil {
-			grpcLog.Errorln("Marchalling error: ", err)
+			// Nothing to post, the transaction's 2 events are counted as failed
+			atomic.AddInt64(&vStats.failed, 2)
+			grpcLog.Errorln("Transaction not constructed   :", reccount, err)
+			return err
 
 		}
+	} else {
+		// We're reading data from files, so simply post data per file/payload
+
+		// returnedRecs is a map of file names, each filename is 2 JSON documents, each of which is a FS Payment (or addProxy) event,
+		// At this point we simply post the events onto the FS end point, and record the response.
 
-		OutboundBytes, err = json.Marshal(t_OutboundPayload)
+		// In soak mode we cycle through the input files
+		filename := fmt.Sprintf("%s%s%s", vGeneral.Input_path, pathSep, returnedRecs[count%len(returnedRecs)])
+
+		if vGeneral.Debuglevel > 2 {
+			grpcLog.Infoln("Source Event                  :", filename)
+
+		}
+		t_OutboundPayload, t_InboundPayload, err = contructFinTransactionFromFile(filename)
 		if err != nil {
-			grpcLog.Errorln("Marchalling error: ", err)
+			// Nothing to post, the transaction's 2 events are counted as failed
+			atomic.AddInt64(&vStats.failed, 2)
+			grpcLog.Errorln("Transaction not constructed   :", reccount, err)
+			return err
 
 		}
+	}
 
-		if vGeneral.Debuglevel > 1 && vGeneral.Echojson == 1 {
+	// The scenario step, for matching cassette interactions across runs, see cassette.go
+	vStep := reccount
+	if vGeneral.Json_from_file == 1 {
+		vStep = returnedRecs[count%len(returnedRecs)]
 
-			if t_InboundPayload["eventType"] == "paymentNRT" || t_InboundPayload["eventType"] == "paymentRT" {
+	}
+	setScenarioStep(t_InboundPayload["transactionId"], vStep)
+	defer clearScenarioStep(t_InboundPayload["transactionId"])
 
-				grpcLog.Infoln("Inbound Payload   	:")
-				prettyJSON(string(InboundBytes))
+	// RTP-* payments are preceded by a request-to-pay event from the creditor's bank
+	var t_RequestToPayPayload map[string]interface{}
+	if vGeneral.Json_from_file == 0 && isRequestToPay(t_InboundPayload["localInstrument"]) {
+		t_RequestToPayPayload = constructRequestToPay(t_OutboundPayload, t_InboundPayload)
 
-				grpcLog.Infoln("")
+	}
 
-				grpcLog.Infoln("Outbound Payload   	:")
-				prettyJSON(string(OutboundBytes))
+	if vGeneral.Debuglevel > 1 {
 
-			} else {
-				grpcLog.Infoln("Outbound Payload   	:")
-				prettyJSON(string(OutboundBytes))
+		// We can display the t_InboundPayload values here as we assigned the same values, inbound payment to be before outbound
+		// to both the inbound and outbound Payloads
+		grpcLog.Infoln("transactionId assigned        :", t_InboundPayload["transactionId"])
+		grpcLog.Infoln("eventTime assigned            :", t_InboundPayload["eventTime"])
+		grpcLog.Infoln("creationDate assigned         :", t_InboundPayload["creationDate"])
 
-				grpcLog.Infoln("")
+		if t_InboundPayload["eventType"] == "paymentNRT" || t_InboundPayload["eventType"] == "paymentRT" {
+			// payment*
+			grpcLog.Infoln("requestExecutionDate assigned :", t_InboundPayload["requestExecutionDate"])
+			grpcLog.Infoln("settlementDate assigned       :", t_InboundPayload["settlementDate"])
 
-				grpcLog.Infoln("Inbound Payload   	:")
-				prettyJSON(string(InboundBytes))
-			}
+			grpcLog.Infoln("")
+			grpcLog.Infoln("Inbound eventId assigned      :", t_InboundPayload["eventId"])
+			grpcLog.Infoln("Outbound eventId assigned     :", t_OutboundPayload["eventId"])
+
+		} else {
+			// addPayee*
+			grpcLog.Infoln("")
+			grpcLog.Infoln("Outbound                      :")
+			grpcLog.Infoln("eventId assigned              :", t_OutboundPayload["eventId"])
+
+			grpcLog.Infoln("")
+			grpcLog.Infoln("Inbound                       :")
+			grpcLog.Infoln("eventId assigned              :", t_InboundPayload["eventId"])
 
 		}
 
-		// At this point we have 2 Payloads, either fake or from source files.
-		// Now lets http post them
-		var vPaymentRTScore float64
-		var vAddPayeeRTScore float64
-		if vGeneral.Call_fs_api == 1 { // POST to API endpoint
+	}
 
-			if vGeneral.Debuglevel > 1 {
-				grpcLog.Info("")
-				grpcLog.Info("Call API Flow")
-				grpcLog.Info("")
-			}
+	InboundBytes, err = json.Marshal(t_InboundPayload)
+	if err != nil {
+		grpcLog.Errorln("Marchalling error: ", err)
 
-			var vParticipant string
-			var vLocalInstrument string
-			var apiInboundStart time.Time
-			var apiOutboundStart time.Time
-			var apiInboundEnd float64
-			var apiOutboundEnd float64
-			var InboundResponse *http.Response
-			var OutboundResponse *http.Response
+	}
 
-			if t_InboundPayload["eventType"] == "paymentNRT" || t_InboundPayload["eventType"] == "paymentRT" {
+	OutboundBytes, err = json.Marshal(t_OutboundPayload)
+	if err != nil {
+		grpcLog.Errorln("Marchalling error: ", err)
 
-				if vGeneral.Debuglevel > 1 {
-					grpcLog.Infoln("payment* Flow")
-				}
+	}
 
-				// We're doing payments, so:
+	if vGeneral.Debuglevel > 1 && vGeneral.Echojson == 1 {
 
-				// Inbound Call Section
-				// 	paymentRT before paymentNRT
-				//
-				// 	paymentRT will have a 200 if successful & paymentNRT will have a 204 if successful.
+		if t_InboundPayload["eventType"] == "paymentNRT" || t_InboundPayload["eventType"] == "paymentRT" {
 
-				apiInboundStart = time.Now()
-				InboundResponse, err = httpCALL(InboundBytes, vGeneral.Httpposturl, client)
-				if err != nil {
-					os.Exit(1)
+			grpcLog.Infoln("Inbound Payload   	:")
+			prettyJSON(string(InboundBytes))
 
-				}
-				apiInboundEnd = time.Since(apiInboundStart).Seconds()
-				defer InboundResponse.Body.Close()
+			grpcLog.Infoln("")
 
-				// We need to do 2 api calls, 1 each for outbound and inbound event.
+			grpcLog.Infoln("Outbound Payload   	:")
+			prettyJSON(string(OutboundBytes))
 
-				// Outbound Call Section
-				// 	paymentNRT
-				//
-				// 	paymentNRT will have a 204 if successful
+		} else {
+			grpcLog.Infoln("Outbound Payload   	:")
+			prettyJSON(string(OutboundBytes))
 
-				apiOutboundStart = time.Now()
-				OutboundResponse, err = httpCALL(OutboundBytes, vGeneral.Httpposturl, client)
-				if err != nil {
-					os.Exit(1)
+			grpcLog.Infoln("")
 
-				}
-				apiOutboundEnd = time.Since(apiOutboundStart).Seconds()
-				defer OutboundResponse.Body.Close()
+			grpcLog.Infoln("Inbound Payload   	:")
+			prettyJSON(string(InboundBytes))
+		}
 
-			} else {
+	}
 
-				if vGeneral.Debuglevel > 1 {
-					grpcLog.Infoln("")
-					grpcLog.Infoln("AddPayee* Flow")
-				}
-				// We're doing addPayee*, so:
+	// The request-to-pay has to be sent, and responded to, before the payment itself. Only accepted requests
+	// result in the paymentRT/paymentNRT pair being posted/written.
+	var vPostPayment = true
+	if t_RequestToPayPayload != nil {
+		vPostPayment, err = processRequestToPay(t_RequestToPayPayload, t_OutboundPayload, t_InboundPayload, reccount, vService, vLag)
+		if err != nil {
+			return err
+
+		}
 
-				// Outbound Call Section
-				// 	addPayeeRT followed by addPayeeNRT
-				//
-				// 	outbound addPayeeRT will have a 200 if successful, inbound will have a 204
+		// The payments were refreshed (eventTime) after the acceptance delay
+		InboundBytes, _ = json.Marshal(t_InboundPayload)
+		OutboundBytes, _ = json.Marshal(t_OutboundPayload)
 
+	}
+
+	// At this point we have 2 Payloads, either fake or from source files.
+	// Now lets http post them
+	var vPaymentRTScore float64
+	var vAddPayeeRTScore float64
+	var vInboundOutcome *engineOutcome
+	var vOutboundOutcome *engineOutcome
+	// Set when the transaction's 2nd event could not be posted
+	var vAbortErr error
+	// The 2nd event, not posted as the 1st was dead-lettered, dead-lettered itself once the 1st's result is written
+	var vUnposted map[string]interface{}
+	if vGeneral.Call_fs_api == 1 && vPostPayment { // POST to API endpoint
+
+		if vGeneral.Debuglevel > 1 {
+			grpcLog.Info("")
+			grpcLog.Info("Call API Flow")
+			grpcLog.Info("")
+		}
+
+		var vParticipant string
+		var vLocalInstrument string
+		var apiInboundStart time.Time
+		var apiOutboundStart time.Time
+		var apiInboundEnd float64
+		var apiOutboundEnd float64
+		var InboundResponse *http.Response
+		var OutboundResponse *http.Response
+		var vInboundRetries int
+		var vOutboundRetries int
+
+		if t_InboundPayload["eventType"] == "paymentNRT" || t_InboundPayload["eventType"] == "paymentRT" {
+
+			if vGeneral.Debuglevel > 1 {
+				grpcLog.Infoln("payment* Flow")
+			}
+
+			// We're doing payments, so:
+
+			// Inbound Call Section
+			// 	paymentRT before paymentNRT
+			//
+			// 	paymentRT will have a 200 if successful & paymentNRT will have a 204 if successful.
+
+			apiInboundStart = time.Now()
+			InboundResponse, vInboundRetries, err = vSink.Send(t_InboundPayload, InboundBytes)
+			if err != nil {
+				abortTransaction(err, vInboundRetries, t_InboundPayload, t_OutboundPayload)
+				return err
+
+			}
+			apiInboundEnd = time.Since(apiInboundStart).Seconds()
+
+			// Read and close the response now, the connection is then back in the pool for the next event and
+			// httpTimeout doesn't run on while we post it
+			jsonDataInboundResponsebody, err = io.ReadAll(InboundResponse.Body)
+			vInboundDeadLettered := err != nil || !eventAccepted(InboundResponse)
+			if err != nil {
+				grpcLog.Errorln("Inbound Body -> io.ReadAll(InboundResponse.Body) error: ", err)
+				writeDeadLetter(t_InboundPayload, InboundResponse, jsonDataInboundResponsebody, vInboundRetries, err)
+
+			}
+			InboundResponse.Body.Close()
+
+			// We need to do 2 api calls, 1 each for outbound and inbound event.
+
+			// Outbound Call Section
+			// 	paymentNRT
+			//
+			// 	paymentNRT will have a 204 if successful
+
+			if vInboundDeadLettered {
+				// Not posted once the inbound event is dead-lettered, a repost then replays the pair in order
+				vUnposted = t_OutboundPayload
+
+			} else {
 				apiOutboundStart = time.Now()
-				OutboundResponse, err = httpCALL(OutboundBytes, vGeneral.Httpposturl, client)
+				OutboundResponse, vOutboundRetries, err = vSink.Send(t_OutboundPayload, OutboundBytes)
 				if err != nil {
-					os.Exit(1)
+					// The inbound event was posted, it's result is still recorded below
+					abortTransaction(err, vOutboundRetries, t_OutboundPayload)
+					vAbortErr = err
 
-				}
-				apiOutboundEnd = time.Since(apiOutboundStart).Seconds()
-				defer OutboundResponse.Body.Close()
+				} else {
+					apiOutboundEnd = time.Since(apiOutboundStart).Seconds()
 
-				// Inbound Call Section
-				apiInboundStart = time.Now()
-				InboundResponse, err = httpCALL(InboundBytes, vGeneral.Httpposturl, client)
-				if err != nil {
-					os.Exit(1)
+					// Read and close the response, as above
+					jsonDataOutboundResponsebody, err = io.ReadAll(OutboundResponse.Body)
+					if err != nil {
+						grpcLog.Errorln("Outbound Body -> io.ReadAll(OutboundResponse.Body) error: ", err)
+						writeDeadLetter(t_OutboundPayload, OutboundResponse, jsonDataOutboundResponsebody, vOutboundRetries, err)
+
+					}
+					OutboundResponse.Body.Close()
 
 				}
-				apiInboundEnd = time.Since(apiInboundStart).Seconds()
-				defer InboundResponse.Body.Close()
+			}
 
+		} else {
+
+			if vGeneral.Debuglevel > 1 {
+				grpcLog.Infoln("")
+				grpcLog.Infoln("AddPayee* Flow")
 			}
+			// We're doing addPayee*, so:
 
-			// http calls done, in required order.
-			// Response Extract section,
+			// Outbound Call Section
+			// 	addPayeeRT followed by addPayeeNRT
+			//
+			// 	outbound addPayeeRT will have a 200 if successful, inbound will have a 204
 
-			// Do something with all the output/response
-			jsonDataInboundResponsebody, err = io.ReadAll(InboundResponse.Body)
+			apiOutboundStart = time.Now()
+			OutboundResponse, vOutboundRetries, err = vSink.Send(t_OutboundPayload, OutboundBytes)
 			if err != nil {
-				grpcLog.Errorln("Inbound Body -> io.ReadAll(InboundResponse.Body) error: ", err)
+				abortTransaction(err, vOutboundRetries, t_OutboundPayload, t_InboundPayload)
+				return err
 
 			}
+			apiOutboundEnd = time.Since(apiOutboundStart).Seconds()
 
+			// Read and close the response before the next event is posted, as for payments
 			jsonDataOutboundResponsebody, err = io.ReadAll(OutboundResponse.Body)
+			vOutboundDeadLettered := err != nil || !eventAccepted(OutboundResponse)
 			if err != nil {
 				grpcLog.Errorln("Outbound Body -> io.ReadAll(OutboundResponse.Body) error: ", err)
+				writeDeadLetter(t_OutboundPayload, OutboundResponse, jsonDataOutboundResponsebody, vOutboundRetries, err)
+
+			}
+			OutboundResponse.Body.Close()
+
+			// Inbound Call Section
+			if vOutboundDeadLettered {
+				// As for payments
+				vUnposted = t_InboundPayload
+
+			} else {
+				apiInboundStart = time.Now()
+				InboundResponse, vInboundRetries, err = vSink.Send(t_InboundPayload, InboundBytes)
+				if err != nil {
+					// The outbound event was posted, it's result is still recorded below
+					abortTransaction(err, vInboundRetries, t_InboundPayload)
+					vAbortErr = err
+
+				} else {
+					apiInboundEnd = time.Since(apiInboundStart).Seconds()
 
+					// Read and close the response, as above
+					jsonDataInboundResponsebody, err = io.ReadAll(InboundResponse.Body)
+					if err != nil {
+						grpcLog.Errorln("Inbound Body -> io.ReadAll(InboundResponse.Body) error: ", err)
+						writeDeadLetter(t_InboundPayload, InboundResponse, jsonDataInboundResponsebody, vInboundRetries, err)
+
+					}
+					InboundResponse.Body.Close()
+
+				}
 			}
 
+		}
+
+		// http calls done, in required order. If the 2nd event was not or could not be posted it's response is nil, the 1st
+		// event was posted (and scored) so it's result is still recorded.
+		if InboundResponse != nil {
+			recordLatency(t_InboundPayload, "inbound", time.Duration(apiInboundEnd*float64(time.Second)), vLag)
+		}
+		if OutboundResponse != nil {
+			recordLatency(t_OutboundPayload, "outbound", time.Duration(apiOutboundEnd*float64(time.Second)), vLag)
+		}
+
+		// Response Extract section, the bodies were read right after each post
+
+		// Do something with all the output/response
+		if InboundResponse != nil {
 			if vGeneral.Debuglevel > 1 {
 				grpcLog.Infoln("")
 				grpcLog.Infoln("Inbound API Call Time         :", apiInboundEnd, "Sec")
-				grpcLog.Infoln("Outbound API Call Time        :", apiOutboundEnd, "Sec")
+				logCallTiming("Inbound API Call Phases       ", callTimingOf(InboundResponse))
+				grpcLog.Infoln("Inbound response Status       :", InboundResponse.Status)
 
 				if vGeneral.Debuglevel > 2 {
-					grpcLog.Infoln("")
 					grpcLog.Infoln("Inbound response Headers      :", InboundResponse.Header)
-					grpcLog.Infoln("")
-					grpcLog.Infoln("Outbound response Headers     :", OutboundResponse.Header)
 
 				}
 			}
+		}
+
+		if OutboundResponse != nil {
 			if vGeneral.Debuglevel > 1 {
 				grpcLog.Infoln("")
-				grpcLog.Infoln("Inbound response Status       :", InboundResponse.Status)
+				grpcLog.Infoln("Outbound API Call Time        :", apiOutboundEnd, "Sec")
+				logCallTiming("Outbound API Call Phases      ", callTimingOf(OutboundResponse))
 				grpcLog.Infoln("Outbound response Status      :", OutboundResponse.Status)
-				grpcLog.Infoln("")
+
+				if vGeneral.Debuglevel > 2 {
+					grpcLog.Infoln("Outbound response Headers     :", OutboundResponse.Header)
+
+				}
 			}
-			// Define a map to hold the JSON data
-			var inboundResponsebodyMap map[string]interface{}
-			_ = json.Unmarshal(jsonDataInboundResponsebody, &inboundResponsebodyMap)
+		}
+
+		// Define a map to hold the JSON data
+		var inboundResponsebodyMap map[string]interface{}
+		_ = json.Unmarshal(jsonDataInboundResponsebody, &inboundResponsebodyMap)
 
-			var outboundResponsebodyMap map[string]interface{}
-			_ = json.Unmarshal(jsonDataOutboundResponsebody, &outboundResponsebodyMap)
+		var outboundResponsebodyMap map[string]interface{}
+		_ = json.Unmarshal(jsonDataOutboundResponsebody, &outboundResponsebodyMap)
 
+		if InboundResponse != nil {
 			if InboundResponse.Status == "200 OK" { // paymentRT
 
 				if t_InboundPayload["eventType"].(string) == "paymentRT" {
 
-					vPaymentRTScore, err = RiskScoreExtract(inboundResponsebodyMap)
+					vInboundOutcome, err = engineOutcomeOf(jsonDataInboundResponsebody)
 					if err != nil {
 						grpcLog.Errorln(err)
 
+					} else {
+						vPaymentRTScore = vInboundOutcome.OverallScore
+						reportEngineOutcome("engineResponse for paymentRT  :", t_InboundPayload, "inbound", vInboundOutcome)
+
 					}
 
 					if vGeneral.Debuglevel > 2 {
@@ -1469,14 +2085,14 @@ func runLoader(arg string) {
 
 			} else if InboundResponse.Status == "204 No Content" {
 
-				// it's either a paymentNRT or addPayeeNRT
+				// it's either a paymentNRT or addPayeeNRT, or with a non HTTP sink any event (see sink.go)
 
 				if vGeneral.Prometheus_enabled == 1 {
 
 					vParticipant = t_InboundPayload["tenantId"].(string)
 					vScore := fmt.Sprintf("%v", "0.0") // for NRT payloads we simply push a 0 score, to comply # variables for the prometheus object call
 
-					if t_InboundPayload["eventType"].(string) == "paymentNRT" {
+					if t_InboundPayload["eventType"] == "paymentRT" || t_InboundPayload["eventType"] == "paymentNRT" {
 
 						vLocalInstrument = t_InboundPayload["localInstrument"].(string)
 
@@ -1489,7 +2105,7 @@ func runLoader(arg string) {
 							"payment_method": vLocalInstrument,
 							"score":          vScore}).Observe(apiInboundEnd)
 
-					} else {
+					} else if t_InboundPayload["eventType"] == "addPayeeRT" || t_InboundPayload["eventType"] == "addPayeeNRT" {
 
 						m.api_addpayee_duration.With(prometheus.Labels{
 							"hostname":    vGeneral.Hostname,
@@ -1525,7 +2141,7 @@ func runLoader(arg string) {
 
 					vParticipant = t_InboundPayload["tenantId"].(string)
 
-					if t_InboundPayload["eventType"].(string) == "paymentRT" || t_InboundPayload["eventType"].(string) == "paymentNRT" {
+					if t_InboundPayload["eventType"] == "paymentRT" || t_InboundPayload["eventType"] == "paymentNRT" {
 
 						m.err_pmnt_processed.With(prometheus.Labels{
 							"hostname":       vGeneral.Hostname,
@@ -1535,7 +2151,7 @@ func runLoader(arg string) {
 							"direction":      "inbound",
 							"payment_method": vLocalInstrument}).Inc()
 
-					} else {
+					} else if t_InboundPayload["eventType"] == "addPayeeRT" || t_InboundPayload["eventType"] == "addPayeeNRT" {
 
 						m.err_addpayee_processed.With(prometheus.Labels{
 							"hostname":    vGeneral.Hostname,
@@ -1553,6 +2169,8 @@ func runLoader(arg string) {
 
 				}
 
+				writeDeadLetter(t_InboundPayload, InboundResponse, jsonDataInboundResponsebody, vInboundRetries, nil)
+
 				tInboundBody = map[string]interface{}{
 					"transactionId":   t_InboundPayload["transactionId"],
 					"eventId":         t_InboundPayload["eventId"],
@@ -1565,6 +2183,14 @@ func runLoader(arg string) {
 				}
 			}
 
+			tInboundBody["step"] = vStep
+			tInboundBody["retries"] = vInboundRetries
+			if vInboundOutcome != nil {
+				tInboundBody["outcome"] = vInboundOutcome
+			}
+			tInboundBody["timings"] = callTimingOf(InboundResponse).report()
+			writeResultLine(t_InboundPayload, time.Duration(apiInboundEnd*float64(time.Second)), tInboundBody, nil)
+
 			// Add is used here rather than Push to not delete a previously pushed
 			// success timestamp in case of a failure of this backup.
 			if vGeneral.Prometheus_enabled == 1 {
@@ -1573,15 +2199,21 @@ func runLoader(arg string) {
 
 				}
 			}
+		}
 
+		if OutboundResponse != nil {
 			if OutboundResponse.Status == "200 OK" { // AddPayeeRT
 				if t_OutboundPayload["eventType"].(string) == "addPayeeRT" {
 
 					// it's a addPayeeRT (only Outbound event that response with a 200 is addPayeeRT event)
-					vAddPayeeRTScore, err = RiskScoreExtract(outboundResponsebodyMap)
+					vOutboundOutcome, err = engineOutcomeOf(jsonDataOutboundResponsebody)
 					if err != nil {
 						grpcLog.Errorln(err)
 
+					} else {
+						vAddPayeeRTScore = vOutboundOutcome.OverallScore
+						reportEngineOutcome("engineResponse for addPayeeRT :", t_OutboundPayload, "outbound", vOutboundOutcome)
+
 					}
 
 					if vGeneral.Debuglevel > 2 {
@@ -1624,16 +2256,16 @@ func runLoader(arg string) {
 					"processTime":     time.Now().UTC(),
 				}
 
-			} else if OutboundResponse.Status == "204 No Content" { // paymentNRT
+			} else if OutboundResponse.Status == "204 No Content" { // paymentNRT, or with a non HTTP sink any event
 
 				if vGeneral.Prometheus_enabled == 1 {
 
 					vParticipant = t_OutboundPayload["tenantId"].(string)
+					vScore := fmt.Sprintf("%v", "0.0") // for NRT payloads we simply push a 0 score, to comply # variables for the prometheus object call
 
-					if t_OutboundPayload["eventType"].(string) == "paymentNRT" {
+					if t_OutboundPayload["eventType"] == "paymentRT" || t_OutboundPayload["eventType"] == "paymentNRT" {
 
 						vLocalInstrument = t_OutboundPayload["localInstrument"].(string)
-						vScore := fmt.Sprintf("%v", "0.0") // for NRT payloads we simply push a 0 score, to comply # variables for the prometheus object call
 
 						m.api_pmnt_duration.With(prometheus.Labels{
 							"hostname":       vGeneral.Hostname,
@@ -1644,6 +2276,15 @@ func runLoader(arg string) {
 							"payment_method": vLocalInstrument,
 							"score":          vScore}).Observe(apiOutboundEnd)
 
+					} else if t_OutboundPayload["eventType"] == "addPayeeRT" || t_OutboundPayload["eventType"] == "addPayeeNRT" {
+
+						m.api_addpayee_duration.With(prometheus.Labels{
+							"hostname":    vGeneral.Hostname,
+							"msg_type":    t_OutboundPayload["eventType"].(string),
+							"service":     vService,
+							"participant": vParticipant,
+							"direction":   "outbound",
+							"score":       vScore}).Observe(apiOutboundEnd)
 					}
 				}
 
@@ -1675,7 +2316,7 @@ func runLoader(arg string) {
 
 					vParticipant = t_OutboundPayload["tenantId"].(string)
 
-					if t_OutboundPayload["eventType"].(string) == "paymentNRT" {
+					if t_OutboundPayload["eventType"] == "paymentRT" || t_OutboundPayload["eventType"] == "paymentNRT" {
 
 						m.err_pmnt_processed.With(prometheus.Labels{
 							"hostname":       vGeneral.Hostname,
@@ -1685,7 +2326,7 @@ func runLoader(arg string) {
 							"direction":      "outbound",
 							"payment_method": vLocalInstrument}).Inc()
 
-					} else {
+					} else if t_OutboundPayload["eventType"] == "addPayeeRT" || t_OutboundPayload["eventType"] == "addPayeeNRT" {
 
 						m.err_addpayee_processed.With(prometheus.Labels{
 							"hostname":    vGeneral.Hostname,
@@ -1703,6 +2344,8 @@ func runLoader(arg string) {
 
 				}
 
+				writeDeadLetter(t_OutboundPayload, OutboundResponse, jsonDataOutboundResponsebody, vOutboundRetries, nil)
+
 				tOutboundBody = map[string]interface{}{
 					"transactionId":   t_OutboundPayload["transactionId"],
 					"eventId":         t_OutboundPayload["eventId"],
@@ -1715,6 +2358,14 @@ func runLoader(arg string) {
 				}
 			}
 
+			tOutboundBody["step"] = vStep
+			tOutboundBody["retries"] = vOutboundRetries
+			if vOutboundOutcome != nil {
+				tOutboundBody["outcome"] = vOutboundOutcome
+			}
+			tOutboundBody["timings"] = callTimingOf(OutboundResponse).report()
+			writeResultLine(t_OutboundPayload, time.Duration(apiOutboundEnd*float64(time.Second)), tOutboundBody, nil)
+
 			// Add is used here rather than Push to not delete a previously pushed
 			// success timestamp in case of a failure of this backup.
 			if vGeneral.Prometheus_enabled == 1 {
@@ -1723,105 +2374,116 @@ func runLoader(arg string) {
 
 				}
 			}
+		}
 
+		if vUnposted != nil {
+			skipUnposted(vUnposted)
 		}
-		// end of the Call_fs_api = 1 processing
 
-		// Output Cycle
-		//
-		// We've posted the payloads and gotten the various forms of responses
-		// and combined the FS response with a larger ..Payload to output to
-		// screen and file.
+	}
+	// end of the Call_fs_api = 1 processing
 
-		//
-		// event if we post to FS API or not, we want isolated control if we output to the engineResponse json output file.
+	// Output Cycle
+	//
+	// We've posted the payloads and gotten the various forms of responses
+	// and combined the FS response with a larger ..Payload to output to
+	// screen and file.
 
-		// We have 2 steps here, first the original posted event, this is controlled by json_to_file,
-		// the 2ne is a always print, which is the api post response
-		TransactionId := t_InboundPayload["transactionId"]
-		OutboundTagId := t_OutboundPayload["eventId"]
-		InboundTagId := t_InboundPayload["eventId"]
+	//
+	// event if we post to FS API or not, we want isolated control if we output to the engineResponse json output file.
 
-		fileStart := time.Now()
+	// We have 2 steps here, first the original posted event, this is controlled by json_to_file,
+	// the 2ne is a always print, which is the api post response
+	TransactionId := t_InboundPayload["transactionId"]
+	OutboundTagId := t_OutboundPayload["eventId"]
+	InboundTagId := t_InboundPayload["eventId"]
 
-		// I'm going to split this into 2 sections.
-		// first is outputting the posted event data to a file <transaction id>-<event id>.json
-		// the second is the http response received, which will go to <transaction id>-<event id>-out.json
+	fileStart := time.Now()
 
-		//...................................
-		// Writing struct type to a JSON file
-		//...................................
-		// Writing
-		// https://www.golangprograms.com/golang-writing-struct-to-json-file.html
-		// https://www.developer.com/languages/json-files-golang/
-		// Reading
-		// https://medium.com/kanoteknologi/better-way-to-read-and-write-json-file-in-golang-9d575b7254f2
+	// I'm going to split this into 2 sections.
+	// first is outputting the posted event data to a file <transaction id>-<event id>.json
+	// the second is the http response received, which will go to <transaction id>-<event id>-out.json
 
-		if vGeneral.Json_to_file == 1 {
+	//...................................
+	// Writing struct type to a JSON file
+	//...................................
+	// Writing
+	// https://www.golangprograms.com/golang-writing-struct-to-json-file.html
+	// https://www.developer.com/languages/json-files-golang/
+	// Reading
+	// https://medium.com/kanoteknologi/better-way-to-read-and-write-json-file-in-golang-9d575b7254f2
 
-			grpcLog.Info("")
-			grpcLog.Info("JSON to File Flow")
+	if vGeneral.Json_to_file == 1 && vPostPayment {
 
-			// We need to do 2 api calls, 1 each for outbound and inbound event.
+		grpcLog.Info("")
+		grpcLog.Info("JSON to File Flow")
 
-			// Inbound
-			// The posted event
-			loc_in := fmt.Sprintf("%s%s%s_%s-%s.json", vGeneral.Output_path, pathSep, reccount, TransactionId, InboundTagId)
-			if vGeneral.Debuglevel > 1 {
-				grpcLog.Infoln("Inbound Output Event          :", loc_in)
+		// We need to do 2 api calls, 1 each for outbound and inbound event.
 
-			}
+		// Inbound
+		// The posted event
+		loc_in := fmt.Sprintf("%s%s%s_%s-%s.json", vGeneral.Output_path, pathSep, reccount, TransactionId, InboundTagId)
+		if vGeneral.Debuglevel > 1 {
+			grpcLog.Infoln("Inbound Output Event          :", loc_in)
 
-			fd_in, err := json.MarshalIndent(t_InboundPayload, "", " ")
-			if err != nil {
-				grpcLog.Errorln("MarshalIndent error", err)
+		}
 
-			}
+		fd_in, err := json.MarshalIndent(t_InboundPayload, "", " ")
+		if err != nil {
+			grpcLog.Errorln("MarshalIndent error", err)
 
-			err = os.WriteFile(loc_in, fd_in, 0644)
-			if err != nil {
-				grpcLog.Errorln("os.WriteFile error A", err)
+		}
 
-			}
+		err = os.WriteFile(loc_in, fd_in, 0644)
+		if err != nil {
+			grpcLog.Errorln("os.WriteFile error A", err)
 
-			// Outbound
-			// The posted event
-			loc_out := fmt.Sprintf("%s%s%s_%s-%s.json", vGeneral.Output_path, pathSep, reccount, TransactionId, OutboundTagId)
-			if vGeneral.Debuglevel > 1 {
-				grpcLog.Infoln("Outbound Output Event         :", loc_out)
+		}
 
-			}
+		// Outbound
+		// The posted event
+		loc_out := fmt.Sprintf("%s%s%s_%s-%s.json", vGeneral.Output_path, pathSep, reccount, TransactionId, OutboundTagId)
+		if vGeneral.Debuglevel > 1 {
+			grpcLog.Infoln("Outbound Output Event         :", loc_out)
 
-			fd_out, err := json.MarshalIndent(t_OutboundPayload, "", " ")
-			if err != nil {
-				grpcLog.Errorln("MarshalIndent error", err)
+		}
 
-			}
+		fd_out, err := json.MarshalIndent(t_OutboundPayload, "", " ")
+		if err != nil {
+			grpcLog.Errorln("MarshalIndent error", err)
 
-			err = os.WriteFile(loc_out, fd_out, 0644)
-			if err != nil {
-				grpcLog.Errorln("os.WriteFile error B", err)
+		}
 
-			}
+		err = os.WriteFile(loc_out, fd_out, 0644)
+		if err != nil {
+			grpcLog.Errorln("os.WriteFile error B", err)
 
 		}
 
-		// Did we call the API endpoint above... if yes and
-		// if engineResponse_to_file == 1 then then do these steps
-		// here we save the result (http code and engineResponse) to a file.
-		if vGeneral.Call_fs_api == 1 {
+	}
+
+	// Did we call the API endpoint above... if yes and
+	// if engineResponse_to_file == 1 then then do these steps
+	// here we save the result (http code and engineResponse) to a file.
+	if vGeneral.Call_fs_api == 1 && vPostPayment {
+
+		var loc_in string
+		var loc_out string
 
-			var loc_in string
-			var loc_out string
+		if vGeneral.EngineResponse_to_file == 1 {
 
-			if vGeneral.EngineResponse_to_file == 1 {
+			grpcLog.Info("")
+			grpcLog.Info("engineResponse to File Flow")
 
-				grpcLog.Info("")
-				grpcLog.Info("engineResponse to File Flow")
+			// In soak mode we cycle through the input files, as above
+			var sourcefile string
+			if vGeneral.Json_from_file == 1 {
+				sourcefile = strings.Split(returnedRecs[count%len(returnedRecs)], ".")[0]
 
-				sourcefile := strings.Split(returnedRecs[count], ".")[0]
+			}
 
-				// inbound engineResponse
+			// inbound engineResponse, nil if the event could not be posted (dead-lettered instead)
+			if tInboundBody != nil {
 				if vGeneral.Json_from_file == 1 {
 					loc_in = fmt.Sprintf("%s%s%s-%s-%s-out.json", vGeneral.Output_path, pathSep, sourcefile, TransactionId, InboundTagId)
 
@@ -1847,8 +2509,10 @@ func runLoader(arg string) {
 					grpcLog.Errorln("os.WriteFile error", err)
 
 				}
+			}
 
-				// outbound engineResponse
+			// outbound engineResponse
+			if tOutboundBody != nil {
 				if vGeneral.Json_from_file == 1 {
 					loc_out = fmt.Sprintf("%s%s%s-%s-%s-out.json", vGeneral.Output_path, pathSep, sourcefile, TransactionId, OutboundTagId)
 
@@ -1872,64 +2536,54 @@ func runLoader(arg string) {
 					grpcLog.Errorln("os.WriteFile error", err)
 
 				}
+			}
 
-				// lets report how long it took us to write data to output files
-				if vGeneral.Debuglevel > 1 {
-					grpcLog.Infoln("JSON to File                  :", time.Since(fileStart).Seconds(), "Sec")
+			// lets report how long it took us to write data to output files
+			if vGeneral.Debuglevel > 1 {
+				grpcLog.Infoln("JSON to File                  :", time.Since(fileStart).Seconds(), "Sec")
 
-				}
 			}
 		}
+	}
 
-		if vGeneral.Debuglevel > 1 {
-			grpcLog.Infoln("Total Time                    :", time.Since(txnStart).Seconds(), "Sec")
+	if vGeneral.Debuglevel > 1 {
+		grpcLog.Infoln("Total Time                    :", time.Since(txnStart).Seconds(), "Sec")
 
-		}
+	}
 
-		//////////////////////////////////////////////////
-		//
-		// THIS IS SLEEP BETWEEN RECORD POSTS
-		//
-		// if 0 then sleep is disabled otherwise
-		//
-		// lets get a random value 0 -> vGeneral.sleep, then delay/sleep as up to that fraction of a second.
-		// this mimics someone thinking, as if this is being done by a human at a keyboard, for batch file processing we don't have this.
-		// ie if the user said 200 then it implies a randam value from 0 -> 200 milliseconds.
-		//
-		// USED TO SLOW THINGS DOWN
-		//
-		//////////////////////////////////////////////////
+	if vAbortErr != nil {
+		return vAbortErr
+	}
+
+	//////////////////////////////////////////////////
+	//
+	// THIS IS SLEEP BETWEEN RECORD POSTS
+	//
+	// if 0 then sleep is disabled otherwise
+	//
+	// lets get a random value 0 -> vGeneral.sleep, then delay/sleep as up to that fraction of a second.
+	// this mimics someone thinking, as if this is being done by a human at a keyboard, for batch file processing we don't have this.
+	// ie if the user said 200 then it implies a randam value from 0 -> 200 milliseconds.
+	//
+	// USED TO SLOW THINGS DOWN
+	//
+	//////////////////////////////////////////////////
 
-		if vGeneral.Sleep != 0 {
-			n := rand.Intn(vGeneral.Sleep) // if vGeneral.sleep = 1000, then n will be random value of 0 -> 1000  aka 0 and 1 second
-			if vGeneral.Debuglevel >= 2 {
-				grpcLog.Infof("Going to sleep for            : %d Milliseconds\n", n)
+	// Not applicable when running open-loop (rateProfile), the scheduler does the pacing.
+	if vGeneral.Sleep != 0 && vGeneral.RateProfile == "" {
+		n := rand.Intn(vGeneral.Sleep) // if vGeneral.sleep = 1000, then n will be random value of 0 -> 1000  aka 0 and 1 second
+		if vGeneral.Debuglevel >= 2 {
+			grpcLog.Infof("Going to sleep for            : %d Milliseconds\n", n)
 
-			}
-			time.Sleep(time.Duration(n) * time.Millisecond)
 		}
-
+		sleepOrStop(time.Duration(n) * time.Millisecond)
 	}
 
-	grpcLog.Infoln("")
-	grpcLog.Infoln("**** DONE Processing ****")
-	grpcLog.Infoln("")
-
-	vEnd := time.Now()
-	vElapse := vEnd.Sub(vStart)
-	grpcLog.Infoln("Start                         : ", vStart)
-	grpcLog.Infoln("End                           : ", vEnd)
-	grpcLog.Infoln("Elapsed Time (Seconds)        : ", vElapse.Seconds())
-	grpcLog.Infoln("Records Processed             : ", todo_count)
-	grpcLog.Infoln(fmt.Sprintf("                              :  %.3f Txns/Second", float64(todo_count)/vElapse.Seconds()))
-	grpcLog.Infoln(fmt.Sprintf("(x2 Txns)                     :  %.3f Events/Second", float64(todo_count)/vElapse.Seconds()*2))
-
-	//		grpcLog.Infoln(fmt.Sprintf("Transactions # / second       :  %.3f Txns/Second", float64(todo_count)/vElapse.Seconds()))
-	//		grpcLog.Infoln(fmt.Sprintf("Events # / second  (x2 Txns)  :  %.3f Events/Sec", float64(todo_count)/vElapse.Seconds()*2))
+	atomic.AddInt64(&vStats.txns, 1)
 
-	grpcLog.Infoln("")
+	return nil
 
-} // runLoader()
+} // processTransaction()
 
 func main() {
 
@@ -1939,7 +2593,34 @@ func main() {
 
 	arg = os.Args[1]
 
-	runLoader(arg)
+	switch arg {
+	case "loaddb":
+		runLoadDB(os.Args[2:])
+
+	case "repost":
+		runRepost(os.Args[2:])
+
+	case "tokenstub":
+		runTokenStub(os.Args[2:])
+
+	case "mock-engine":
+		runMockEngine(os.Args[2:])
+
+	case "diff":
+		runDiff(os.Args[2:])
+
+	case "runs":
+		runRuns(os.Args[2:])
+
+	default:
+		for _, flag := range os.Args[2:] {
+			if flag == "--update-golden" {
+				vUpdateGolden = true
+			}
+		}
+		runLoader(arg)
+
+	}
 
 	grpcLog.Info("****** Completed          *****")
 
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+
+	"cmd/types"
+)
+
+func TestMergeTenants(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		tenants []types.TTenant
+		want    []types.TTenant
+		wantErr bool
+	}{
+		{
+			name:    "single range",
+			tenants: []types.TTenant{{TenantId: "A", BranchRangeStart: 100, BranchRangeEnd: 199}},
+			want:    []types.TTenant{{TenantId: "A", BranchRangeStart: 100, BranchRangeEnd: 199, BranchRanges: []types.TBranchRange{{Start: 100, End: 199}}}},
+		},
+		{
+			name: "duplicates merged",
+			tenants: []types.TTenant{
+				{TenantId: "A", BranchRangeStart: 100, BranchRangeEnd: 199, SpecialBranches: []string{"900"}},
+				{TenantId: "B", BranchRangeStart: 1, BranchRangeEnd: 1},
+				{TenantId: "A", BranchRangeStart: 300, BranchRangeEnd: 399, Bicfi: "AAAAZAJJ", SpecialBranches: []string{"900", "901"}},
+			},
+			want: []types.TTenant{
+				{TenantId: "A", BranchRangeStart: 100, BranchRangeEnd: 199, Bicfi: "AAAAZAJJ", SpecialBranches: []string{"900", "901"},
+					BranchRanges: []types.TBranchRange{{Start: 100, End: 199}, {Start: 300, End: 399}}},
+				{TenantId: "B", BranchRangeStart: 1, BranchRangeEnd: 1, BranchRanges: []types.TBranchRange{{Start: 1, End: 1}}},
+			},
+		},
+		{
+			name:    "explicit ranges",
+			tenants: []types.TTenant{{TenantId: "A", BranchRanges: []types.TBranchRange{{Start: 1, End: 5}, {Start: 10, End: 10}}}},
+			want:    []types.TTenant{{TenantId: "A", BranchRanges: []types.TBranchRange{{Start: 1, End: 5}, {Start: 10, End: 10}}}},
+		},
+		{
+			name:    "inverted range",
+			tenants: []types.TTenant{{TenantId: "A", BranchRangeStart: 199, BranchRangeEnd: 100}},
+			wantErr: true,
+		},
+		{
+			name:    "inverted explicit range",
+			tenants: []types.TTenant{{TenantId: "A", BranchRanges: []types.TBranchRange{{Start: 1, End: 5}, {Start: 9, End: 8}}}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mergeTenants(tt.tenants)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("mergeTenants() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeTenants() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFakeBranchId(t *testing.T) {
+
+	tests := []struct {
+		name        string
+		tenant      types.TTenant
+		specialRate int
+		valid       func(int) bool
+	}{
+		{
+			name:   "single range",
+			tenant: types.TTenant{BranchRangeStart: 100, BranchRangeEnd: 199},
+			valid:  func(n int) bool { return n >= 100 && n <= 199 },
+		},
+		{
+			name:   "multiple ranges",
+			tenant: types.TTenant{BranchRanges: []types.TBranchRange{{Start: 1, End: 3}, {Start: 500, End: 500}}},
+			valid:  func(n int) bool { return (n >= 1 && n <= 3) || n == 500 },
+		},
+		{
+			name:        "special branches always",
+			tenant:      types.TTenant{BranchRangeStart: 100, BranchRangeEnd: 199, SpecialBranches: []string{"900", "901"}},
+			specialRate: 100,
+			valid:       func(n int) bool { return n == 900 || n == 901 },
+		},
+		{
+			name:        "special branches never",
+			tenant:      types.TTenant{BranchRangeStart: 100, BranchRangeEnd: 199, SpecialBranches: []string{"900"}},
+			specialRate: 0,
+			valid:       func(n int) bool { return n >= 100 && n <= 199 },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vGeneral.SpecialBranchRate = tt.specialRate
+			defer func() { vGeneral.SpecialBranchRate = 0 }()
+
+			for i := 0; i < 200; i++ {
+				id := fakeBranchId(tt.tenant)
+				n, err := strconv.Atoi(id)
+				if err != nil || !tt.valid(n) {
+					t.Fatalf("fakeBranchId() = %q, outside the tenant's ranges", id)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+/*****************************************************************************
+*
+*	File			: provider.go
+*
+* 	Created			: 19 Oct 2026
+*
+*	Description		: Account/Tenant provider, sits behind the account and tenant lookups done during fake data generation.
+*					: accountSource = seed		=> accounts and tenants from the seed file (varSeed), as before.
+*					: accountSource = sqlite	=> accounts and tenants from a local SQLite database (accountDB), see provider_sqlite.go
+*
+*					: Accounts can be filtered by tenant, good/bad status and account type (AccountIDCode), and either be
+*					: picked at random or streamed in order (accountStream = 1) for the debtor side.
+*
+*	By				: George Leonard (georgelza@gmail.com)
+*
+*****************************************************************************/
+
+package main
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/brianvoe/gofakeit"
+
+	"cmd/types"
+)
+
+const (
+	accountsGood = "good"
+	accountsBad  = "bad"
+	accountsAny  = "any"
+)
+
+type AccountFilter struct {
+	TenantId      string // "" => all tenants
+	Status        string // good, bad or any
+	AccountIDCode string // "" => all account types
+}
+
+type AccountProvider interface {
+	RandomAccount(filter AccountFilter) (types.TAccount, error)
+	StreamAccounts(filter AccountFilter) (<-chan types.TAccount, error)
+	FindTenant(datamode string, tenantId string) (types.TTenant, error)
+	Close() error
+}
+
+var (
+	vAccounts     AccountProvider
+	accountStream <-chan types.TAccount
+	streamMu      sync.Mutex
+)
+
+// Build the provider as per accountSource
+func newAccountProvider() (AccountProvider, error) {
+
+	switch vGeneral.AccountSource {
+	case "", "seed":
+		return &seedProvider{seed: &varSeed}, nil
+
+	case "sqlite":
+		return openSQLiteProvider(vGeneral.AccountDB)
+
+	}
+
+	return nil, errors.New("unknown accountSource: " + vGeneral.AccountSource)
+}
+
+// The filter as configured in *_app.json
+func configuredAccountFilter() AccountFilter {
+
+	filter := AccountFilter{
+		TenantId:      vGeneral.AccountTenant,
+		Status:        vGeneral.AccountStatus,
+		AccountIDCode: vGeneral.AccountType,
+	}
+	if filter.Status == "" {
+		filter.Status = accountsGood
+	}
+
+	return filter
+}
+
+// Next debtor account from the account stream, once the stream is exhausted we start again from the top.
+func nextStreamedAccount(filter AccountFilter) (types.TAccount, error) {
+
+	streamMu.Lock()
+	defer streamMu.Unlock()
+
+	for tries := 0; tries < 2; tries++ {
+		if accountStream != nil {
+			if account, ok := <-accountStream; ok {
+				return account, nil
+			}
+		}
+
+		stream, err := vAccounts.StreamAccounts(filter)
+		if err != nil {
+			return types.TAccount{}, err
+		}
+		accountStream = stream
+	}
+
+	return types.TAccount{}, errors.New("no accounts found for filter")
+}
+
+// seedProvider, accounts and tenants as read from the seed file.
+type seedProvider struct {
+	seed *types.TPSeed
+}
+
+func (p *seedProvider) accounts(filter AccountFilter) (accounts []types.TAccount) {
+
+	var source []types.TAccount
+	switch filter.Status {
+	case accountsBad:
+		source = p.seed.Accounts.Bad
+
+	case accountsAny:
+		source = append(append(source, p.seed.Accounts.Good...), p.seed.Accounts.Bad...)
+
+	default:
+		source = p.seed.Accounts.Good
+
+	}
+
+	for _, account := range source {
+		if filter.TenantId != "" && account.TenantId != filter.TenantId {
+			continue
+		}
+		if filter.AccountIDCode != "" && account.AccountIDCode != filter.AccountIDCode {
+			continue
+		}
+		accounts = append(accounts, account)
+	}
+
+	return accounts
+}
+
+func (p *seedProvider) RandomAccount(filter AccountFilter) (types.TAccount, error) {
+
+	accounts := p.accounts(filter)
+	if len(accounts) == 0 {
+		return types.TAccount{}, errors.New("no accounts found for filter")
+	}
+
+	return accounts[gofakeit.Number(0, len(accounts)-1)], nil
+}
+
+func (p *seedProvider) StreamAccounts(filter AccountFilter) (<-chan types.TAccount, error) {
+
+	accounts := p.accounts(filter)
+	if len(accounts) == 0 {
+		return nil, errors.New("no accounts found for filter")
+	}
+
+	stream := make(chan types.TAccount)
+	go func() {
+		defer close(stream)
+		for _, account := range accounts {
+			stream <- account
+		}
+	}()
+
+	return stream, nil
+}
+
+func (p *seedProvider) FindTenant(datamode string, tenantId string) (types.TTenant, error) {
+
+	if datamode == "hist" {
+		return findTenant(p.seed.Tenants.Nrt, tenantId)
+	}
+
+	return findTenant(p.seed.Tenants.Rt, tenantId)
+}
+
+func (p *seedProvider) Close() error {
+	return nil
+}
@@ -0,0 +1,352 @@
+/*****************************************************************************
+*
+*	File			: provider_sqlite.go
+*
+* 	Created			: 19 Oct 2026
+*
+*	Description		: SQLite (file based, no server) account/tenant provider, able to hold millions of accounts, way
+*					: past what we can keep in memory via TPSeed.
+*
+*					: The database is created/loaded using:
+*					:	fs_producer loaddb <env> [fake account count]
+*					: which copies the tenants and accounts from the seed file into accountDB, and optionally adds
+*					: <fake account count> generated accounts spread across the seed tenants. A reload replaces the
+*					: database's tenants and accounts, it does not add to them.
+*
+*	By				: George Leonard (georgelza@gmail.com)
+*
+*****************************************************************************/
+
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/brianvoe/gofakeit"
+
+	// Pure Go SQLite driver, no cgo, so we can still cross compile the Windows exe
+	_ "modernc.org/sqlite"
+
+	"cmd/types"
+)
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS tenants (
+	datamode		TEXT NOT NULL,
+	tenantid		TEXT NOT NULL,
+	name			TEXT,
+	bicfi			TEXT,
+	branchranges	TEXT,
+	specialbranches	TEXT,
+	PRIMARY KEY (datamode, tenantid)
+);
+
+CREATE TABLE IF NOT EXISTS accounts (
+	id				TEXT NOT NULL PRIMARY KEY,
+	status			TEXT NOT NULL,
+	tenantid		TEXT,
+	accountnumber	TEXT,
+	accountidcode	TEXT,
+	name			TEXT,
+	address			TEXT,
+	proxyid			TEXT,
+	proxytype		TEXT,
+	proxydomain		TEXT
+);
+
+CREATE INDEX IF NOT EXISTS accounts_status_idx ON accounts (status, tenantid, accountidcode);
+`
+
+const accountColumns = "id, status, tenantid, accountnumber, accountidcode, name, address, proxyid, proxytype, proxydomain"
+
+type sqliteProvider struct {
+	db *sql.DB
+
+	// rowid range per filter, saves us a count/min/max per random pick
+	mu     sync.Mutex
+	ranges map[AccountFilter][2]int64
+}
+
+func openSQLiteProvider(fileName string) (*sqliteProvider, error) {
+
+	db, err := sql.Open("sqlite", fileName)
+	if err != nil {
+		return nil, fmt.Errorf("sql.Open error %s: %s", fileName, err)
+	}
+
+	if _, err = db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("schema create error %s: %s", fileName, err)
+	}
+
+	return &sqliteProvider{db: db, ranges: make(map[AccountFilter][2]int64)}, nil
+}
+
+func (p *sqliteProvider) where(filter AccountFilter) (string, []interface{}) {
+
+	var clauses []string
+	var args []interface{}
+
+	if filter.Status != "" && filter.Status != accountsAny {
+		clauses = append(clauses, "status = ?")
+		args = append(args, filter.Status)
+	}
+	if filter.TenantId != "" {
+		clauses = append(clauses, "tenantid = ?")
+		args = append(args, filter.TenantId)
+	}
+	if filter.AccountIDCode != "" {
+		clauses = append(clauses, "accountidcode = ?")
+		args = append(args, filter.AccountIDCode)
+	}
+	if len(clauses) == 0 {
+		return "1 = 1", args
+	}
+
+	return strings.Join(clauses, " AND "), args
+}
+
+func scanAccount(row interface{ Scan(...interface{}) error }) (account types.TAccount, err error) {
+
+	var status, name, address string
+
+	err = row.Scan(&account.Id, &status, &account.TenantId, &account.AccountNumber, &account.AccountIDCode, &name, &address,
+		&account.ProxyId, &account.ProxyType, &account.ProxyDomain)
+	if err != nil {
+		return account, err
+	}
+
+	_ = json.Unmarshal([]byte(name), &account.Name)
+	_ = json.Unmarshal([]byte(address), &account.Address)
+
+	return account, nil
+}
+
+// Random pick, we choose a random rowid between the min and max rowid matching the filter and take the first matching
+// row from there, this avoids a OFFSET scan on large tables.
+func (p *sqliteProvider) RandomAccount(filter AccountFilter) (types.TAccount, error) {
+
+	where, args := p.where(filter)
+
+	p.mu.Lock()
+	bounds, ok := p.ranges[filter]
+	if !ok {
+		var min, max sql.NullInt64
+		err := p.db.QueryRow("SELECT min(rowid), max(rowid) FROM accounts WHERE "+where, args...).Scan(&min, &max)
+		if err != nil || !min.Valid {
+			p.mu.Unlock()
+			return types.TAccount{}, errors.New("no accounts found for filter")
+		}
+		bounds = [2]int64{min.Int64, max.Int64}
+		p.ranges[filter] = bounds
+	}
+	p.mu.Unlock()
+
+	rowid := bounds[0] + rand.Int63n(bounds[1]-bounds[0]+1)
+
+	// NOT INDEXED, walk forward on rowid from the random starting point, else the planner picks the status index and sorts
+	row := p.db.QueryRow("SELECT "+accountColumns+" FROM accounts NOT INDEXED WHERE "+where+" AND rowid >= ? ORDER BY rowid LIMIT 1", append(args, rowid)...)
+	account, err := scanAccount(row)
+	if err == sql.ErrNoRows {
+		row = p.db.QueryRow("SELECT "+accountColumns+" FROM accounts WHERE "+where+" ORDER BY rowid LIMIT 1", args...)
+		account, err = scanAccount(row)
+	}
+
+	return account, err
+}
+
+// Stream all accounts matching the filter, in rowid order, without holding them all in memory.
+func (p *sqliteProvider) StreamAccounts(filter AccountFilter) (<-chan types.TAccount, error) {
+
+	where, args := p.where(filter)
+
+	rows, err := p.db.Query("SELECT "+accountColumns+" FROM accounts WHERE "+where+" ORDER BY rowid", args...)
+	if err != nil {
+		return nil, err
+	}
+
+	stream := make(chan types.TAccount, 100)
+	go func() {
+		defer close(stream)
+		defer rows.Close()
+		for rows.Next() {
+			account, err := scanAccount(rows)
+			if err != nil {
+				grpcLog.Errorln("Account stream scan error: ", err)
+				return
+			}
+			stream <- account
+		}
+	}()
+
+	return stream, nil
+}
+
+func (p *sqliteProvider) FindTenant(datamode string, tenantId string) (tenant types.TTenant, err error) {
+
+	var ranges, special string
+
+	if datamode != "hist" {
+		datamode = "rpp"
+	}
+
+	err = p.db.QueryRow("SELECT tenantid, name, bicfi, branchranges, specialbranches FROM tenants WHERE datamode = ? AND tenantid = ?",
+		datamode, tenantId).Scan(&tenant.TenantId, &tenant.Name, &tenant.Bicfi, &ranges, &special)
+	if err != nil {
+		return tenant, errors.New("tenant not found")
+	}
+
+	_ = json.Unmarshal([]byte(ranges), &tenant.BranchRanges)
+	_ = json.Unmarshal([]byte(special), &tenant.SpecialBranches)
+	if len(tenant.BranchRanges) > 0 {
+		tenant.BranchRangeStart = tenant.BranchRanges[0].Start
+		tenant.BranchRangeEnd = tenant.BranchRanges[0].End
+	}
+
+	return tenant, nil
+}
+
+func (p *sqliteProvider) Close() error {
+	return p.db.Close()
+}
+
+// Replace the database's tenants and accounts with the seed's, and add fakeCount generated accounts.
+func (p *sqliteProvider) load(seed types.TPSeed, fakeCount int) error {
+
+	tx, err := p.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// A re-load replaces, else every account is duplicated, skewing the random and stream picks. Also clears databases
+	// created before accounts.id was the primary key.
+	for _, table := range []string{"tenants", "accounts"} {
+		if _, err = tx.Exec("DELETE FROM " + table); err != nil {
+			return err
+		}
+	}
+
+	tenantStmt, err := tx.Prepare("INSERT OR REPLACE INTO tenants (datamode, tenantid, name, bicfi, branchranges, specialbranches) VALUES (?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer tenantStmt.Close()
+
+	for datamode, tenants := range map[string][]types.TTenant{"rpp": seed.Tenants.Rt, "hist": seed.Tenants.Nrt} {
+		for _, tenant := range tenants {
+			ranges, _ := json.Marshal(tenant.BranchRanges)
+			special, _ := json.Marshal(tenant.SpecialBranches)
+			if _, err = tenantStmt.Exec(datamode, tenant.TenantId, tenant.Name, tenant.Bicfi, string(ranges), string(special)); err != nil {
+				return err
+			}
+		}
+	}
+
+	accountStmt, err := tx.Prepare("INSERT OR REPLACE INTO accounts (" + accountColumns + ") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer accountStmt.Close()
+
+	insert := func(status string, account types.TAccount) error {
+		name, _ := json.Marshal(account.Name)
+		address, _ := json.Marshal(account.Address)
+		_, err := accountStmt.Exec(account.Id, status, account.TenantId, account.AccountNumber, account.AccountIDCode, string(name), string(address),
+			account.ProxyId, account.ProxyType, account.ProxyDomain)
+		return err
+	}
+
+	for _, account := range seed.Accounts.Good {
+		if err = insert(accountsGood, account); err != nil {
+			return err
+		}
+	}
+	for _, account := range seed.Accounts.Bad {
+		if err = insert(accountsBad, account); err != nil {
+			return err
+		}
+	}
+
+	// Fake accounts, spread over the seed tenants, using the seed accounts as templates for the account types
+	if fakeCount > 0 && len(seed.Tenants.Rt) > 0 && len(seed.Accounts.Good) > 0 {
+		gofakeit.Seed(0)
+		for i := 0; i < fakeCount; i++ {
+			template := seed.Accounts.Good[gofakeit.Number(0, len(seed.Accounts.Good)-1)]
+			tenant := seed.Tenants.Rt[gofakeit.Number(0, len(seed.Tenants.Rt)-1)]
+
+			account := types.TAccount{
+				Id:            "F" + strconv.Itoa(i+1),
+				TenantId:      tenant.TenantId,
+				AccountNumber: strconv.Itoa(gofakeit.Number(1000000000, 1999999999)),
+				AccountIDCode: template.AccountIDCode,
+				Name: types.TName{
+					NamePrefix: gofakeit.NamePrefix(),
+					FullName:   gofakeit.FirstName(),
+					Surname:    gofakeit.LastName(),
+				},
+				Address:     template.Address,
+				ProxyId:     "0" + strconv.Itoa(gofakeit.Number(600000000, 849999999)),
+				ProxyType:   template.ProxyType,
+				ProxyDomain: template.ProxyDomain,
+			}
+
+			status := accountsGood
+			if gofakeit.Number(1, 100) <= 2 {
+				status = accountsBad
+			}
+
+			if err = insert(status, account); err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// fs_producer loaddb <env> [fake account count]
+func runLoadDB(args []string) {
+
+	if len(args) < 1 {
+		grpcLog.Fatalln("Usage: fs_producer loaddb <env> [fake account count]")
+
+	}
+
+	vGeneral = loadConfig(args[0])
+	varSeed = loadSeed(vGeneral.SeedFile)
+
+	fakeCount := 0
+	if len(args) > 1 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil {
+			grpcLog.Fatalln("Invalid fake account count: ", args[1])
+
+		}
+		fakeCount = n
+	}
+
+	provider, err := openSQLiteProvider(vGeneral.AccountDB)
+	if err != nil {
+		grpcLog.Fatalln(err)
+
+	}
+	defer provider.Close()
+
+	grpcLog.Infoln("Loading seed into             :", vGeneral.AccountDB)
+
+	if err = provider.load(varSeed, fakeCount); err != nil {
+		grpcLog.Fatalln("Account database load error: ", err)
+
+	}
+
+	grpcLog.Infoln("Accounts loaded               :", len(varSeed.Accounts.Good)+len(varSeed.Accounts.Bad)+fakeCount)
+
+}
@@ -0,0 +1,548 @@
+/*****************************************************************************
+*
+*	File			: report.go
+*
+* 	Created			: 19 Oct 2026
+*
+*	Description		: Run report, a single self-contained static HTML file (inline CSS and SVG, no external assets)
+*					: written at the end of the run, to attach to a ticket or mail around:
+*					:	- the run summary and the effective config, secrets redacted
+*					:	- a timeline of the API calls, call time against time into the run, failures in red
+*					:	- the score distribution per eventType/direction
+*					:	- the latency percentiles (as results_file)
+*					:	- the rule hit matrix (as ruleMatrix_file)
+*					:	- the failures, with their request and response bodies (as the dead-letter files)
+*					:	- the engine versions seen
+*
+*					: report_file "" => off. To bound memory in soak runs the timeline keeps the first
+*					: reportTimelineMax calls and the report the first reportFailuresMax failures.
+*
+*	By				: George Leonard (georgelza@gmail.com)
+*
+*****************************************************************************/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"math"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	reportTimelineMax = 5000
+	reportFailuresMax = 100
+	reportScoreBins   = 10
+	reportRedacted    = "*****"
+)
+
+// Config keys (Tp_general field names) never written to the report.
+var reportSecrets = map[string]bool{
+	"Pkcs12_password":   true,
+	"AuthToken":         true,
+	"AuthKey":           true,
+	"AuthSecret":        true,
+	"OAuthClientSecret": true,
+}
+
+type timelineCall struct {
+	At        time.Time
+	EventType string
+	Direction string
+	CallTime  time.Duration
+}
+
+var (
+	reportMu       sync.Mutex
+	vTimeline      []timelineCall
+	vTimelineDrops int
+	vFailures      []deadLetter
+	vFailuresDrops int
+)
+
+// Record a API call for the timeline.
+func recordTimeline(eventType string, direction string, callTime time.Duration) {
+
+	if vGeneral.Report_file == "" {
+		return
+	}
+
+	reportMu.Lock()
+	defer reportMu.Unlock()
+
+	if len(vTimeline) >= reportTimelineMax {
+		vTimelineDrops++
+		return
+	}
+	vTimeline = append(vTimeline, timelineCall{At: time.Now().Add(-callTime), EventType: eventType, Direction: direction, CallTime: callTime})
+}
+
+// Record a failed event, with it's request and response.
+func recordFailure(record deadLetter) {
+
+	if vGeneral.Report_file == "" {
+		return
+	}
+
+	reportMu.Lock()
+	defer reportMu.Unlock()
+
+	if len(vFailures) >= reportFailuresMax {
+		vFailuresDrops++
+		return
+	}
+	vFailures = append(vFailures, record)
+}
+
+type reportRow struct {
+	Key   string
+	Value string
+}
+
+type reportPoint struct {
+	X, Y   float64
+	Colour string
+	Title  string
+}
+
+type reportLegend struct {
+	Label  string
+	Colour string
+}
+
+type reportBin struct {
+	Range string
+	Count int
+	Width float64 // % of the largest bin
+}
+
+type reportDistribution struct {
+	EventType string
+	Direction string
+	Count     int
+	Bins      []reportBin
+}
+
+type reportFailure struct {
+	TransactionId string
+	EventId       string
+	EventType     string
+	Status        string
+	Error         string
+	Retries       int
+	FailedAt      string
+	Request       string
+	Response      string
+	at            time.Time
+}
+
+type reportVersion struct {
+	Versions string
+	Events   int
+}
+
+type reportData struct {
+	Title         string
+	Generated     string
+	Summary       []reportRow
+	Config        []reportRow
+	Width, Height float64
+	Plot          struct{ X0, X1, Y0, Y1 float64 }
+	XTicks        []reportPoint
+	YTicks        []reportPoint
+	Points        []reportPoint
+	Legend        []reportLegend
+	TimelineDrops int
+	Distributions []reportDistribution
+	Latencies     []latencyResult
+	Matrix        [][]string
+	Tenants       [][]string
+	Failures      []reportFailure
+	FailuresDrops int
+	Versions      []reportVersion
+}
+
+var reportPalette = []string{"#1f77b4", "#2ca02c", "#ff7f0e", "#9467bd", "#8c564b", "#e377c2", "#17becf", "#bcbd22"}
+
+const reportFailed = "#d62728"
+
+// The effective config, secrets redacted, also written to the run manifest (see runs.go).
+func redactedConfig() map[string]interface{} {
+
+	var config map[string]interface{}
+	fj, _ := json.Marshal(vGeneral)
+	if err := json.Unmarshal(fj, &config); err != nil {
+		return nil
+	}
+
+	for key, value := range config {
+		text, ok := value.(string)
+		if !ok || text == "" {
+			continue
+		}
+
+		if reportSecrets[key] {
+			config[key] = reportRedacted
+		}
+		if key == "ProxyURL" {
+			if u, err := url.Parse(text); err == nil {
+				config[key] = u.Redacted()
+			}
+		}
+	}
+
+	return config
+}
+
+// The effective config, as key/value rows, secrets redacted.
+func reportConfig() (rows []reportRow) {
+
+	for key, value := range redactedConfig() {
+		text, ok := value.(string)
+		if !ok {
+			vj, _ := json.Marshal(value)
+			text = string(vj)
+		}
+
+		rows = append(rows, reportRow{Key: key, Value: text})
+	}
+	sort.Slice(rows, func(i, j int) bool { return strings.ToLower(rows[i].Key) < strings.ToLower(rows[j].Key) })
+
+	return rows
+}
+
+// Scale the timeline onto the SVG plot area, the failures are plotted on the x axis at their failure time.
+func (data *reportData) timeline(start time.Time) {
+
+	data.Width, data.Height = 1000, 320
+	data.Plot.X0, data.Plot.X1, data.Plot.Y0, data.Plot.Y1 = 60, 980, 20, 280
+
+	reportMu.Lock()
+	calls := append([]timelineCall{}, vTimeline...)
+	data.TimelineDrops = vTimelineDrops
+	reportMu.Unlock()
+
+	if len(calls) == 0 && len(data.Failures) == 0 {
+		return
+	}
+
+	maxAt, maxMs := 1.0, 1.0
+	labels := make(map[string]bool)
+	for _, c := range calls {
+		maxAt = math.Max(maxAt, c.At.Sub(start).Seconds())
+		maxMs = math.Max(maxMs, float64(c.CallTime.Microseconds())/1000)
+		labels[c.EventType+" "+c.Direction] = true
+	}
+	for _, f := range data.Failures {
+		maxAt = math.Max(maxAt, f.at.Sub(start).Seconds())
+	}
+
+	x := func(s float64) float64 { return data.Plot.X0 + math.Max(0, s)/maxAt*(data.Plot.X1-data.Plot.X0) }
+	y := func(ms float64) float64 { return data.Plot.Y1 - ms/maxMs*(data.Plot.Y1-data.Plot.Y0) }
+
+	for i := 0; i <= 4; i++ {
+		s := maxAt * float64(i) / 4
+		ms := maxMs * float64(i) / 4
+		data.XTicks = append(data.XTicks, reportPoint{X: x(s), Y: data.Plot.Y1 + 16, Title: fmt.Sprintf("%.1fs", s)})
+		data.YTicks = append(data.YTicks, reportPoint{X: data.Plot.X0 - 6, Y: y(ms) + 4, Title: fmt.Sprintf("%.0fms", ms)})
+	}
+
+	colours := make(map[string]string)
+	for i, label := range sortedKeys(labels) {
+		colours[label] = reportPalette[i%len(reportPalette)]
+		data.Legend = append(data.Legend, reportLegend{Label: label, Colour: colours[label]})
+	}
+
+	for _, c := range calls {
+		s := c.At.Sub(start).Seconds()
+		ms := float64(c.CallTime.Microseconds()) / 1000
+		label := c.EventType + " " + c.Direction
+
+		data.Points = append(data.Points, reportPoint{X: x(s), Y: y(ms), Colour: colours[label], Title: fmt.Sprintf("%s %.2fms at %.3fs", label, ms, s)})
+	}
+
+	if len(data.Failures) > 0 {
+		data.Legend = append(data.Legend, reportLegend{Label: "failed", Colour: reportFailed})
+	}
+	for _, f := range data.Failures {
+		s := f.at.Sub(start).Seconds()
+		data.Points = append(data.Points, reportPoint{X: x(s), Y: data.Plot.Y1, Colour: reportFailed, Title: fmt.Sprintf("%s %s failed at %.3fs", f.EventType, f.TransactionId, s)})
+	}
+}
+
+// Score histograms per eventType/direction, reportScoreBins equal bins from 0 to the highest score seen.
+func reportDistributions(outcomes []stepOutcome) (distributions []reportDistribution) {
+
+	scores := make(map[string][]float64)
+	for _, o := range outcomes {
+		key := o.EventType + "|" + o.Direction
+		scores[key] = append(scores[key], o.Outcome.OverallScore)
+	}
+
+	keys := make(map[string]bool)
+	for key := range scores {
+		keys[key] = true
+	}
+
+	for _, key := range sortedKeys(keys) {
+		parts := strings.SplitN(key, "|", 2)
+		d := reportDistribution{EventType: parts[0], Direction: parts[1], Count: len(scores[key])}
+
+		var maxScore float64
+		for _, s := range scores[key] {
+			maxScore = math.Max(maxScore, s)
+		}
+		if maxScore <= 0 {
+			maxScore = 1
+		}
+		width := maxScore / reportScoreBins
+
+		counts := make([]int, reportScoreBins)
+		for _, s := range scores[key] {
+			bin := int(s / width)
+			if bin >= reportScoreBins {
+				bin = reportScoreBins - 1
+			}
+			if bin < 0 {
+				bin = 0
+			}
+			counts[bin]++
+		}
+
+		var most int
+		for _, c := range counts {
+			if c > most {
+				most = c
+			}
+		}
+		for i, c := range counts {
+			bin := reportBin{Range: fmt.Sprintf("%g - %g", float64(i)*width, float64(i+1)*width), Count: c}
+			if most > 0 {
+				bin.Width = float64(c) / float64(most) * 100
+			}
+			d.Bins = append(d.Bins, bin)
+		}
+
+		distributions = append(distributions, d)
+	}
+
+	return distributions
+}
+
+// The distinct engine versions seen, with the number of events.
+func reportVersions(outcomes []stepOutcome) (versions []reportVersion) {
+
+	counts := make(map[string]int)
+	for _, o := range outcomes {
+		if o.Outcome.Versions == nil {
+			continue
+		}
+		vj, _ := json.Marshal(o.Outcome.Versions)
+		counts[string(vj)]++
+	}
+
+	for v, n := range counts {
+		versions = append(versions, reportVersion{Versions: v, Events: n})
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].Events > versions[j].Events })
+
+	return versions
+}
+
+func reportFailures() (failures []reportFailure, drops int) {
+
+	reportMu.Lock()
+	defer reportMu.Unlock()
+
+	for _, f := range vFailures {
+		request := string(f.Request)
+		var indented bytes.Buffer
+		if json.Indent(&indented, f.Request, "", " ") == nil {
+			request = indented.String()
+		}
+
+		status := f.ResponseStatus
+		if status == "" {
+			status = "no response"
+		}
+
+		failures = append(failures, reportFailure{
+			TransactionId: fmt.Sprintf("%v", f.TransactionId),
+			EventId:       fmt.Sprintf("%v", f.EventId),
+			EventType:     fmt.Sprintf("%v", f.EventType),
+			Status:        status,
+			Error:         f.Error,
+			Retries:       f.Retries,
+			FailedAt:      f.FailedAt.Format(time.RFC3339Nano),
+			Request:       request,
+			Response:      f.ResponseBody,
+			at:            f.FailedAt,
+		})
+	}
+
+	return failures, vFailuresDrops
+}
+
+// Write the HTML report, at the end of the run.
+func reportRun(start time.Time, end time.Time) {
+
+	if vGeneral.Report_file == "" {
+		return
+	}
+
+	data := reportData{
+		Title:     fmt.Sprintf("fs_producer run, %s, %s", vGeneral.Hostname, start.Format(time.RFC3339)),
+		Generated: time.Now().Format(time.RFC3339),
+		Config:    reportConfig(),
+	}
+
+	elapsed := end.Sub(start).Seconds()
+	txns := atomic.LoadInt64(&vStats.txns)
+	events := atomic.LoadInt64(&vStats.events)
+	data.Summary = []reportRow{
+		{"Start", start.Format(time.RFC3339Nano)},
+		{"End", end.Format(time.RFC3339Nano)},
+		{"Elapsed Time (Seconds)", fmt.Sprintf("%.3f", elapsed)},
+		{"Workers", fmt.Sprintf("%d", vGeneral.Workers)},
+		{"Records Processed", fmt.Sprintf("%d", txns)},
+		{"Events Posted", fmt.Sprintf("%d", events)},
+		{"Retries", fmt.Sprintf("%d", atomic.LoadInt64(&vStats.retries))},
+		{"Events Failed", fmt.Sprintf("%d", atomic.LoadInt64(&vStats.failed))},
+		{"Txns/Second", fmt.Sprintf("%.3f", float64(txns)/elapsed)},
+		{"Events/Second", fmt.Sprintf("%.3f", float64(events)/elapsed)},
+	}
+	if stopping() {
+		data.Summary = append(data.Summary, reportRow{"Interrupted by", stopReason()})
+	}
+
+	data.Failures, data.FailuresDrops = reportFailures()
+	data.timeline(start)
+
+	outcomes := runOutcomes()
+	data.Distributions = reportDistributions(outcomes)
+	data.Versions = reportVersions(outcomes)
+	data.Latencies = latencyResults()
+	data.Matrix, data.Tenants = ruleMatrixTables()
+
+	var buf bytes.Buffer
+	if err := reportTemplate.Execute(&buf, data); err != nil {
+		grpcLog.Errorln("Report template error", err)
+		return
+
+	}
+
+	if err := os.WriteFile(vGeneral.Report_file, buf.Bytes(), 0644); err != nil {
+		grpcLog.Errorln("Report write error", vGeneral.Report_file, err)
+		return
+
+	}
+
+	grpcLog.Infoln("Report written to             :", vGeneral.Report_file)
+}
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"ms": func(v float64) string { return fmt.Sprintf("%.2f", v) },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; font-size: 13px; margin: 24px; color: #222; }
+h1 { font-size: 20px; } h2 { font-size: 16px; margin-top: 32px; border-bottom: 1px solid #ccc; }
+table { border-collapse: collapse; margin: 8px 0; }
+th, td { border: 1px solid #ddd; padding: 3px 8px; text-align: left; vertical-align: top; }
+th { background: #f3f3f3; }
+td.n { text-align: right; font-variant-numeric: tabular-nums; }
+td.A { background: #f4b6b6; text-align: center; } td.F { background: #fde3a7; text-align: center; }
+.bar { background: #1f77b4; height: 12px; }
+.legend span { display: inline-block; margin-right: 16px; }
+.legend i { display: inline-block; width: 10px; height: 10px; margin-right: 4px; }
+pre { background: #f7f7f7; padding: 6px; max-height: 300px; overflow: auto; white-space: pre-wrap; word-break: break-all; }
+details { margin: 6px 0; } summary { cursor: pointer; }
+.muted { color: #777; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="muted">Generated {{.Generated}}</p>
+
+<h2>Summary</h2>
+<table>{{range .Summary}}<tr><th>{{.Key}}</th><td>{{.Value}}</td></tr>{{end}}</table>
+
+<h2>Timeline</h2>
+{{if .Points}}
+<svg xmlns="http://www.w3.org/2000/svg" width="{{.Width}}" height="{{.Height}}" viewBox="0 0 {{.Width}} {{.Height}}">
+<line x1="{{.Plot.X0}}" y1="{{.Plot.Y1}}" x2="{{.Plot.X1}}" y2="{{.Plot.Y1}}" stroke="#999"/>
+<line x1="{{.Plot.X0}}" y1="{{.Plot.Y0}}" x2="{{.Plot.X0}}" y2="{{.Plot.Y1}}" stroke="#999"/>
+{{range .XTicks}}<text x="{{.X}}" y="{{.Y}}" font-size="10" text-anchor="middle">{{.Title}}</text>{{end}}
+{{range .YTicks}}<text x="{{.X}}" y="{{.Y}}" font-size="10" text-anchor="end">{{.Title}}</text>{{end}}
+{{range .Points}}<circle cx="{{.X}}" cy="{{.Y}}" r="2" fill="{{.Colour}}" fill-opacity="0.7"><title>{{.Title}}</title></circle>{{end}}
+</svg>
+<div class="legend">{{range .Legend}}<span><i style="background: {{.Colour}}"></i>{{.Label}}</span>{{end}}</div>
+<p class="muted">API call time (ms) against time into the run (s).{{if .TimelineDrops}} {{.TimelineDrops}} later calls not shown.{{end}}</p>
+{{else}}<p class="muted">No API calls recorded.</p>{{end}}
+
+<h2>Score distribution</h2>
+{{range .Distributions}}
+<h3>{{.EventType}} {{.Direction}} ({{.Count}} events)</h3>
+<table><tr><th>overallScore</th><th>events</th><th style="width: 300px"></th></tr>
+{{range .Bins}}<tr><td>{{.Range}}</td><td class="n">{{.Count}}</td><td><div class="bar" style="width: {{.Width}}%"></div></td></tr>{{end}}
+</table>
+{{else}}<p class="muted">No scored events.</p>{{end}}
+
+<h2>Latency (ms)</h2>
+{{if .Latencies}}
+<table>
+<tr><th>eventType</th><th>direction</th><th>tenant</th><th>count</th><th>mean</th><th>p50</th><th>p90</th><th>p95</th><th>p99</th><th>p99.9</th><th>max</th><th>corrected p99</th></tr>
+{{range .Latencies}}<tr><td>{{.EventType}}</td><td>{{.Direction}}</td><td>{{.Tenant}}</td><td class="n">{{.Service.Count}}</td><td class="n">{{ms .Service.Mean}}</td>
+<td class="n">{{ms (index .Service.Percentiles "p50")}}</td><td class="n">{{ms (index .Service.Percentiles "p90")}}</td><td class="n">{{ms (index .Service.Percentiles "p95")}}</td>
+<td class="n">{{ms (index .Service.Percentiles "p99")}}</td><td class="n">{{ms (index .Service.Percentiles "p99.9")}}</td><td class="n">{{ms .Service.Max}}</td>
+<td class="n">{{with .Corrected}}{{ms (index .Percentiles "p99")}}{{end}}</td></tr>
+{{end}}
+</table>
+{{else}}<p class="muted">No API calls recorded.</p>{{end}}
+
+<h2>Rule hit matrix</h2>
+<p class="muted">A = fired and alerted, F = fired</p>
+<table>
+{{range $i, $line := .Matrix}}<tr>{{range $line}}{{if eq $i 0}}<th>{{.}}</th>{{else if or (eq . "A") (eq . "F")}}<td class="{{.}}">{{.}}</td>{{else}}<td>{{.}}</td>{{end}}{{end}}</tr>
+{{end}}
+</table>
+<h3>Per tenant, rows fired/alerted</h3>
+<table>
+{{range $i, $line := .Tenants}}<tr>{{range $line}}{{if eq $i 0}}<th>{{.}}</th>{{else}}<td>{{.}}</td>{{end}}{{end}}</tr>
+{{end}}
+</table>
+
+<h2>Failures</h2>
+{{range .Failures}}
+<details>
+<summary>{{.FailedAt}} {{.EventType}} {{.TransactionId}} / {{.EventId}}: {{.Status}}{{if .Error}}, {{.Error}}{{end}} ({{.Retries}} retries)</summary>
+<p>Request</p><pre>{{.Request}}</pre>
+{{if .Response}}<p>Response</p><pre>{{.Response}}</pre>{{end}}
+</details>
+{{else}}<p class="muted">No failures.</p>{{end}}
+{{if .FailuresDrops}}<p class="muted">{{.FailuresDrops}} later failures not shown, see the dead-letter directory.</p>{{end}}
+
+<h2>Engine versions</h2>
+{{if .Versions}}
+<table><tr><th>versions</th><th>events</th></tr>
+{{range .Versions}}<tr><td><code>{{.Versions}}</code></td><td class="n">{{.Events}}</td></tr>{{end}}
+</table>
+{{else}}<p class="muted">No versions in the engineResponses.</p>{{end}}
+
+<h2>Config</h2>
+<table>{{range .Config}}<tr><th>{{.Key}}</th><td><code>{{.Value}}</code></td></tr>{{end}}</table>
+</body>
+</html>
+`))
@@ -0,0 +1,220 @@
+/*****************************************************************************
+*
+*	File			: resultslog.go
+*
+* 	Created			: 19 Oct 2026
+*
+*	Description		: Consolidated results, a alternative to json_to_file/engineResponse_to_file's 2 to 4 files per
+*					: transaction, a single JSONL file with a line per posted event:
+*					:	request, direction, step, callTime (ms), retries, responseStatus, responseHeaders,
+*					:	responseBody, outcome (score, riskStatus, triggered/alerted rules) and timings (phases, ms)
+*					: Events not posted (no response after retries, or a earlier event of the transaction failed) get a
+*					: line with responseResult "NOT POSTED" and the error.
+*
+*					: Every line is written (and with gzip sync-flushed) immediately, a tail -f / crash doesn't lose any.
+*
+*					: resultsLog_file		"" => off, in the run directory when runs_path is set (see runs.go)
+*					: resultsLogGzip		1 => gzip compressed, ".gz" is appended to the file name
+*					: resultsLogMaxSize		MB, when a file reaches it the next is opened, results.jsonl then
+*					:						results.1.jsonl, results.2.jsonl, ... 0 => no rotation
+*
+*	By				: George Leonard (georgelza@gmail.com)
+*
+*****************************************************************************/
+
+package main
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Counts the bytes written to the file, compressed when gzip'ed.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+
+	return n, err
+}
+
+type resultsLog struct {
+	mu    sync.Mutex
+	file  *os.File
+	count *countingWriter
+	gz    *gzip.Writer
+	index int // rotation, 0 => the configured file name
+	lines int64
+}
+
+var vResultsLog *resultsLog
+
+// results.jsonl, results.1.jsonl, results.2.jsonl ...
+func resultsLogName(index int) string {
+
+	name := vGeneral.ResultsLog_file
+	if index > 0 {
+		ext := filepath.Ext(name)
+		name = fmt.Sprintf("%s.%d%s", strings.TrimSuffix(name, ext), index, ext)
+	}
+	if vGeneral.ResultsLogGzip == 1 {
+		name += ".gz"
+	}
+
+	return name
+}
+
+func openResultsLog() error {
+
+	if vGeneral.ResultsLog_file == "" {
+		return nil
+	}
+
+	vResultsLog = &resultsLog{}
+
+	return vResultsLog.open()
+}
+
+func (l *resultsLog) open() error {
+
+	fd, err := os.OpenFile(resultsLogName(l.index), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("resultsLog_file open error %s: %s", resultsLogName(l.index), err)
+	}
+
+	info, err := fd.Stat()
+	if err != nil {
+		fd.Close()
+		return err
+	}
+
+	l.file = fd
+	l.count = &countingWriter{w: fd, n: info.Size()}
+	l.gz = nil
+	if vGeneral.ResultsLogGzip == 1 {
+		l.gz = gzip.NewWriter(l.count)
+	}
+
+	return nil
+}
+
+func (l *resultsLog) close() error {
+
+	if l.gz != nil {
+		if err := l.gz.Close(); err != nil {
+			l.file.Close()
+			return err
+		}
+	}
+
+	return l.file.Close()
+}
+
+func (l *resultsLog) write(line []byte) error {
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if vGeneral.ResultsLogMaxSize > 0 && l.count.n >= int64(vGeneral.ResultsLogMaxSize)*1024*1024 {
+		if err := l.close(); err != nil {
+			return err
+		}
+		l.index++
+		if err := l.open(); err != nil {
+			return err
+		}
+		if vGeneral.Debuglevel > 1 {
+			grpcLog.Infoln("Results log rotated to        :", resultsLogName(l.index))
+
+		}
+	}
+
+	line = append(line, '\n')
+
+	if l.gz == nil {
+		_, err := l.count.Write(line)
+		l.lines++
+		return err
+	}
+
+	if _, err := l.gz.Write(line); err != nil {
+		return err
+	}
+	l.lines++
+
+	return l.gz.Flush()
+}
+
+// Write a event's line, tBody is the response body as for the -out.json files (see processTransaction/postRTPEvent),
+// nil if the event was not posted.
+func writeResultLine(t_Payload map[string]interface{}, callTime time.Duration, tBody map[string]interface{}, err error) {
+
+	if vResultsLog == nil {
+		return
+	}
+
+	line := map[string]interface{}{
+		"transactionId": t_Payload["transactionId"],
+		"eventId":       t_Payload["eventId"],
+		"eventType":     t_Payload["eventType"],
+		"tenantId":      t_Payload["tenantId"],
+		"direction":     payloadDirection(t_Payload),
+		"request":       t_Payload,
+		"loggedAt":      time.Now().UTC(),
+	}
+	_, line["step"] = cassetteKey(t_Payload)
+
+	for key, value := range tBody {
+		line[key] = value
+	}
+
+	if tBody == nil {
+		line["responseResult"] = "NOT POSTED"
+	} else {
+		line["callTime"] = float64(callTime.Microseconds()) / 1000
+	}
+	if err != nil {
+		line["error"] = err.Error()
+	}
+
+	fj, err := json.Marshal(line)
+	if err != nil {
+		grpcLog.Errorln("Results log marshal error", err)
+		return
+
+	}
+
+	if err = vResultsLog.write(fj); err != nil {
+		grpcLog.Errorln("Results log write error", err)
+
+	}
+}
+
+func closeResultsLog() {
+
+	if vResultsLog == nil {
+		return
+	}
+
+	vResultsLog.mu.Lock()
+	defer vResultsLog.mu.Unlock()
+
+	if err := vResultsLog.close(); err != nil {
+		grpcLog.Errorln("Results log close error", err)
+
+	}
+
+	grpcLog.Infoln("Results log written to        :", resultsLogName(vResultsLog.index), vResultsLog.lines, "events")
+}
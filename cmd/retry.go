@@ -0,0 +1,115 @@
+/*****************************************************************************
+*
+*	File			: retry.go
+*
+* 	Created			: 19 Oct 2026
+*
+*	Description		: Classification of transient API failures and the retry backoff used by httpCALL.
+*
+*					: Retried	: connection errors, timeouts, 429 Too Many Requests and 5xx responses
+*					: Not		: anything else, incl. TLS/certificate failures and 4xx (bad payload won't get better)
+*
+*					: Backoff is exponential with full jitter, retryBackoff * 2^attempt capped at retryMaxBackoff (ms),
+*					: a Retry-After header on a 429/503 overrides the calculated value, but is capped at retryMaxBackoff
+*					: too, a gateway asking for an hour would otherwise park the worker for that hour.
+*					: The same request body is re-posted, so the eventId stays the same and the engine can dedupe.
+*
+*	By				: George Leonard (georgelza@gmail.com)
+*
+*****************************************************************************/
+
+package main
+
+import (
+	"crypto/x509"
+	"errors"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Is this failure worth retrying, and why (used as the metric label).
+func classifyFailure(Response *http.Response, err error) (transient bool, reason string) {
+
+	if err != nil {
+		var certErr x509.UnknownAuthorityError
+		var hostErr x509.HostnameError
+		var invalidErr x509.CertificateInvalidError
+		if errors.As(err, &certErr) || errors.As(err, &hostErr) || errors.As(err, &invalidErr) {
+			return false, "tls"
+		}
+
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return true, "timeout"
+		}
+
+		return true, "connection"
+	}
+
+	switch {
+	case Response.StatusCode == http.StatusTooManyRequests:
+		return true, "429"
+
+	case Response.StatusCode >= 500:
+		return true, "5xx"
+
+	}
+
+	return false, ""
+}
+
+// How long to wait before retry number attempt (0 based).
+func retryBackoff(attempt int, Response *http.Response) time.Duration {
+
+	max := time.Duration(vGeneral.RetryMaxBackoff) * time.Millisecond
+	if max <= 0 {
+		max = 10 * time.Second
+	}
+
+	if Response != nil {
+		if wait, ok := retryAfter(Response.Header.Get("Retry-After")); ok {
+			if wait > max {
+				grpcLog.Warningln("Retry-After capped            :", Response.Header.Get("Retry-After"), "=>", max.Seconds(), "Sec")
+				wait = max
+			}
+			return wait
+		}
+	}
+
+	base := time.Duration(vGeneral.RetryBackoff) * time.Millisecond
+	if base <= 0 {
+		base = 100 * time.Millisecond
+	}
+
+	backoff := base << uint(attempt)
+	if backoff > max || backoff <= 0 {
+		backoff = max
+	}
+
+	return time.Duration(rand.Int63n(int64(backoff) + 1))
+}
+
+// Retry-After is either delay-seconds or a HTTP-date.
+func retryAfter(value string) (time.Duration, bool) {
+
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if at, err := http.ParseTime(value); err == nil {
+		wait := time.Until(at)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"testing"
+	"time"
+
+	"cmd/types"
+)
+
+func TestClassifyFailure(t *testing.T) {
+
+	status := func(code int) *http.Response { return &http.Response{StatusCode: code} }
+
+	tests := []struct {
+		name          string
+		response      *http.Response
+		err           error
+		wantTransient bool
+		wantReason    string
+	}{
+		{"unknown authority", nil, &url.Error{Op: "Post", Err: x509.UnknownAuthorityError{}}, false, "tls"},
+		{"hostname mismatch", nil, &url.Error{Op: "Post", Err: x509.HostnameError{Host: "fs"}}, false, "tls"},
+		{"expired certificate", nil, &url.Error{Op: "Post", Err: x509.CertificateInvalidError{Reason: x509.Expired}}, false, "tls"},
+		{"timeout", nil, &url.Error{Op: "Post", Err: &net.DNSError{IsTimeout: true}}, true, "timeout"},
+		{"connection refused", nil, &url.Error{Op: "Post", Err: &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}}, true, "connection"},
+		{"other error", nil, errors.New("EOF"), true, "connection"},
+		{"200", status(200), nil, false, ""},
+		{"204", status(204), nil, false, ""},
+		{"400", status(400), nil, false, ""},
+		{"429", status(429), nil, true, "429"},
+		{"500", status(500), nil, true, "5xx"},
+		{"503", status(503), nil, true, "5xx"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transient, reason := classifyFailure(tt.response, tt.err)
+			if transient != tt.wantTransient || reason != tt.wantReason {
+				t.Errorf("classifyFailure() = %v, %q, want %v, %q", transient, reason, tt.wantTransient, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+
+	withHeader := func(value string) *http.Response {
+		return &http.Response{StatusCode: 429, Header: http.Header{"Retry-After": []string{value}}}
+	}
+
+	tests := []struct {
+		name     string
+		backoff  int
+		max      int
+		attempt  int
+		response *http.Response
+		wantMin  time.Duration
+		wantMax  time.Duration
+	}{
+		{name: "defaults first attempt", attempt: 0, wantMax: 100 * time.Millisecond},
+		{name: "defaults capped", attempt: 20, wantMax: 10 * time.Second},
+		{name: "exponential", backoff: 50, max: 10000, attempt: 3, wantMax: 400 * time.Millisecond},
+		{name: "capped at max", backoff: 50, max: 300, attempt: 10, wantMax: 300 * time.Millisecond},
+		{name: "shift overflow capped", backoff: 50, max: 300, attempt: 70, wantMax: 300 * time.Millisecond},
+		{name: "retry-after seconds", backoff: 50, max: 5000, response: withHeader("2"), wantMin: 2 * time.Second, wantMax: 2 * time.Second},
+		{name: "retry-after capped at max", backoff: 50, max: 5000, response: withHeader("3600"), wantMin: 5 * time.Second, wantMax: 5 * time.Second},
+		{name: "retry-after capped at default max", response: withHeader("3600"), wantMin: 10 * time.Second, wantMax: 10 * time.Second},
+		{name: "retry-after date capped", backoff: 50, max: 5000, response: withHeader(time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)), wantMin: 5 * time.Second, wantMax: 5 * time.Second},
+		{name: "retry-after invalid", backoff: 50, max: 300, response: withHeader("soon"), wantMax: 50 * time.Millisecond},
+		{name: "no retry-after", backoff: 50, max: 300, response: &http.Response{StatusCode: 503}, wantMax: 50 * time.Millisecond},
+	}
+
+	defer func(saved types.Tp_general) { vGeneral = saved }(vGeneral)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vGeneral.RetryBackoff = tt.backoff
+			vGeneral.RetryMaxBackoff = tt.max
+
+			for i := 0; i < 100; i++ {
+				wait := retryBackoff(tt.attempt, tt.response)
+				if wait < tt.wantMin || wait > tt.wantMax {
+					t.Fatalf("retryBackoff(%d) = %v, want %v - %v", tt.attempt, wait, tt.wantMin, tt.wantMax)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,386 @@
+/*****************************************************************************
+*
+*	File			: rtp.go
+*
+* 	Created			: 19 Oct 2026
+*
+*	Description		: Request-to-Pay (RTP-PBPX / RTP-PBAC) handling. For RTP instruments the creditor's bank first sends a
+*					: request-to-pay event, which is then followed by the normal paymentRT/paymentNRT pair.
+*
+*					: Two phase flow:
+*					:	1. creditor's bank sends requestToPay
+*					:	2. after rtpAcceptDelay (random 0 -> N milliseconds) the request is either
+*					:		accepted	=> debtor's bank sends requestToPayResponse (ACCP), followed by paymentRT/paymentNRT
+*					:		rejected	=> debtor's bank sends requestToPayResponse (RJCT), no payment
+*					:		expired		=> creditor's bank records requestToPayResponse (EXPD), no payment
+*					:	   as per rtpAcceptRate and rtpRejectRate, the remainder expire.
+*					:	All events are linked via requestToPayReference.
+*
+*	By				: George Leonard (georgelza@gmail.com)
+*
+*****************************************************************************/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	requestToPayEventType         = "requestToPay"
+	requestToPayResponseEventType = "requestToPayResponse"
+
+	rtpAccepted = "ACCP"
+	rtpRejected = "RJCT"
+	rtpExpired  = "EXPD"
+)
+
+// Is this a RTP-* local instrument
+func isRequestToPay(localInstrument interface{}) bool {
+
+	vLocalInstrument, ok := localInstrument.(string)
+
+	return ok && strings.HasPrefix(vLocalInstrument, "RTP-")
+}
+
+// Build the request-to-pay event as sent by the creditor's bank, using the already constructed payment pair.
+// The inbound payment is the creditor bank's view, so Account => Creditor, CounterParty => Debtor, which is what
+// we want for the request, it's just the direction and from/to that flips.
+//
+// The request and both payment events are linked via requestToPayReference.
+func constructRequestToPay(t_OutboundPayment map[string]interface{}, t_InboundPayment map[string]interface{}) (t_RequestToPay map[string]interface{}) {
+
+	requestToPayId := uuid.New().String()
+
+	t_RequestToPay = map[string]interface{}{
+		"eventId":               uuid.New().String(),
+		"eventType":             requestToPayEventType,
+		"eventTime":             t_InboundPayment["eventTime"],
+		"creationDate":          t_InboundPayment["creationDate"],
+		"direction":             "outbound",
+		"tenantId":              t_InboundPayment["tenantId"],
+		"fromId":                t_InboundPayment["toId"],
+		"toId":                  t_InboundPayment["fromId"],
+		"transactionId":         t_InboundPayment["transactionId"],
+		"localInstrument":       t_InboundPayment["localInstrument"],
+		"amount":                t_InboundPayment["amount"],
+		"paymentReference":      t_InboundPayment["paymentReference"],
+		"msgType":               "RTP",
+		"msgStatus":             "New",
+		"schemaVersion":         1,
+		"requestToPayId":        requestToPayId,
+		"requestToPayReference": requestToPayId,
+		"expiryDateTime":        time.Now().Add(time.Duration(vGeneral.RtpExpiryMinutes) * time.Minute).Format("2006-01-02T15:04:05"),
+	}
+
+	for key, value := range t_InboundPayment {
+		if strings.HasPrefix(key, "account") || strings.HasPrefix(key, "counterparty") {
+			t_RequestToPay[key] = value
+		}
+	}
+
+	t_OutboundPayment["requestToPayReference"] = requestToPayId
+	t_InboundPayment["requestToPayReference"] = requestToPayId
+
+	return t_RequestToPay
+}
+
+// Decide how the debtor's bank responds to the request, rtpAcceptRate % accepted, rtpRejectRate % rejected, rest expire.
+// Neither rate configured (older *_app.json files) => all accepted, as before the request-to-pay phase was added.
+func rtpOutcome() string {
+	return rtpOutcomeOf(rand.Intn(100))
+}
+
+// The outcome for a roll n of 0 -> 99, the accept rate first, a reject rate beyond 100% is cut short.
+func rtpOutcomeOf(n int) string {
+
+	acceptRate := vGeneral.RtpAcceptRate
+	if acceptRate == 0 && vGeneral.RtpRejectRate == 0 {
+		acceptRate = 100
+	}
+
+	if n < acceptRate {
+		return rtpAccepted
+
+	} else if n < acceptRate+vGeneral.RtpRejectRate {
+		return rtpRejected
+
+	}
+
+	return rtpExpired
+}
+
+// Build the response to the request-to-pay. Accepted/rejected responses come from the debtor's bank, an expired request
+// is recorded by the creditor's bank, we post it now rather than wait for the request's expiryDateTime, so it's eventTime
+// is now as well, not the (future) expiryDateTime.
+func constructRequestToPayResponse(t_RequestToPay map[string]interface{}, t_OutboundPayment map[string]interface{}, outcome string) (t_RequestToPayResponse map[string]interface{}) {
+
+	t_RequestToPayResponse = map[string]interface{}{
+		"eventId":               uuid.New().String(),
+		"eventType":             requestToPayResponseEventType,
+		"eventTime":             time.Now().Format("2006-01-02T15:04:05"),
+		"creationDate":          time.Now().Format("2006-01-02T15:04:05"),
+		"direction":             "outbound",
+		"tenantId":              t_OutboundPayment["tenantId"],
+		"fromId":                t_RequestToPay["toId"],
+		"toId":                  t_RequestToPay["fromId"],
+		"transactionId":         t_RequestToPay["transactionId"],
+		"localInstrument":       t_RequestToPay["localInstrument"],
+		"amount":                t_RequestToPay["amount"],
+		"paymentReference":      t_RequestToPay["paymentReference"],
+		"msgType":               "RTPRSP",
+		"msgStatus":             outcome,
+		"schemaVersion":         1,
+		"requestToPayId":        t_RequestToPay["requestToPayId"],
+		"requestToPayReference": t_RequestToPay["requestToPayReference"],
+	}
+
+	switch outcome {
+	case rtpRejected:
+		t_RequestToPayResponse["msgStatusReason"] = "Request declined by debtor"
+
+	case rtpExpired:
+		t_RequestToPayResponse["tenantId"] = t_RequestToPay["tenantId"]
+		t_RequestToPayResponse["fromId"] = t_RequestToPay["fromId"]
+		t_RequestToPayResponse["toId"] = t_RequestToPay["toId"]
+		t_RequestToPayResponse["expiryDateTime"] = t_RequestToPay["expiryDateTime"]
+		t_RequestToPayResponse["msgStatusReason"] = "Request expired before debtor response"
+
+	}
+
+	return t_RequestToPayResponse
+}
+
+// Run the request-to-pay phase, returns true if the request was accepted, implying the payment pair must now be
+// posted. On acceptance the payment eventTime/creationDate's are moved to after the acceptance delay.
+// err is set if a event could not be posted, the transaction was then abandoned (see abortTransaction). A event
+// that was dead-lettered (non 200/204 response) ends the transaction as well, the remaining events are dead-lettered
+// unposted, so a repost replays the transaction in order.
+func processRequestToPay(t_RequestToPay map[string]interface{}, t_OutboundPayment map[string]interface{}, t_InboundPayment map[string]interface{}, reccount string, vService string, lag time.Duration) (accepted bool, err error) {
+
+	retries, deadLettered, err := postRTPEvent(t_RequestToPay, reccount, vService, lag)
+	if err != nil {
+		abortTransaction(err, retries, t_RequestToPay, t_InboundPayment, t_OutboundPayment)
+		return false, err
+
+	}
+
+	if vGeneral.RtpAcceptDelay != 0 && !deadLettered {
+		n := rand.Intn(vGeneral.RtpAcceptDelay)
+		if vGeneral.Debuglevel >= 2 {
+			grpcLog.Infof("RTP acceptance delay          : %d Milliseconds\n", n)
+
+		}
+		// Cut short on a shutdown, the transaction is completed without the remaining delay
+		sleepOrStop(time.Duration(n) * time.Millisecond)
+	}
+
+	outcome := rtpOutcome()
+	if vGeneral.Debuglevel > 1 {
+		grpcLog.Infoln("RequestToPay outcome          :", outcome)
+
+	}
+
+	t_RequestToPayResponse := constructRequestToPayResponse(t_RequestToPay, t_OutboundPayment, outcome)
+	if deadLettered {
+		if outcome == rtpAccepted {
+			skipUnposted(t_RequestToPayResponse, t_InboundPayment, t_OutboundPayment)
+
+		} else {
+			skipUnposted(t_RequestToPayResponse)
+
+		}
+		return false, nil
+	}
+
+	retries, deadLettered, err = postRTPEvent(t_RequestToPayResponse, reccount, vService, lag)
+	if err != nil {
+		abortTransaction(err, retries, t_RequestToPayResponse, t_InboundPayment, t_OutboundPayment)
+		return false, err
+
+	}
+
+	if outcome != rtpAccepted {
+		return false, nil
+	}
+
+	if deadLettered {
+		skipUnposted(t_InboundPayment, t_OutboundPayment)
+		return false, nil
+	}
+
+	eventTime := time.Now().Format("2006-01-02T15:04:05")
+	t_OutboundPayment["eventTime"] = eventTime
+	t_InboundPayment["eventTime"] = eventTime
+	t_OutboundPayment["creationDate"] = eventTime
+	t_InboundPayment["creationDate"] = eventTime
+
+	return true, nil
+}
+
+// Post a request-to-pay phase event (if Call_fs_api = 1) and write the event and response to file as per json_to_file and
+// engineResponse_to_file. Returns the API error if the event could not be posted at all, deadLettered if it was posted
+// but not accepted.
+func postRTPEvent(t_RequestToPay map[string]interface{}, reccount string, vService string, lag time.Duration) (retries int, deadLettered bool, err error) {
+
+	var tRequestToPayBody map[string]interface{}
+
+	RequestToPayBytes, err := json.Marshal(t_RequestToPay)
+	if err != nil {
+		grpcLog.Errorln("Marchalling error: ", err)
+
+	}
+
+	if vGeneral.Debuglevel > 1 {
+		grpcLog.Infoln("requestToPayId assigned       :", t_RequestToPay["requestToPayId"])
+		grpcLog.Infoln("RequestToPay eventId assigned :", t_RequestToPay["eventId"])
+
+		if vGeneral.Echojson == 1 {
+			grpcLog.Infoln("RequestToPay Payload   	:")
+			prettyJSON(string(RequestToPayBytes))
+
+		}
+	}
+
+	if vGeneral.Call_fs_api == 1 {
+
+		apiStart := time.Now()
+		var Response *http.Response
+		Response, retries, err = vSink.Send(t_RequestToPay, RequestToPayBytes)
+		if err != nil {
+			return retries, false, err
+
+		}
+		apiEnd := time.Since(apiStart).Seconds()
+		recordLatency(t_RequestToPay, "outbound", time.Duration(apiEnd*float64(time.Second)), lag)
+
+		jsonDataResponsebody, err := io.ReadAll(Response.Body)
+		if err != nil {
+			grpcLog.Errorln("RequestToPay Body -> io.ReadAll(Response.Body) error: ", err)
+			writeDeadLetter(t_RequestToPay, Response, jsonDataResponsebody, retries, err)
+
+		}
+		Response.Body.Close()
+		deadLettered = err != nil || !eventAccepted(Response)
+
+		var responsebodyMap map[string]interface{}
+		_ = json.Unmarshal(jsonDataResponsebody, &responsebodyMap)
+
+		if vGeneral.Debuglevel > 1 {
+			grpcLog.Infoln("RequestToPay API Call Time    :", apiEnd, "Sec")
+			grpcLog.Infoln("RequestToPay response Status  :", Response.Status)
+			logCallTiming("RequestToPay API Call Phases  ", callTimingOf(Response))
+
+		}
+
+		tRequestToPayBody = map[string]interface{}{
+			"transactionId":   t_RequestToPay["transactionId"],
+			"eventId":         t_RequestToPay["eventId"],
+			"eventType":       t_RequestToPay["eventType"],
+			"responseStatus":  Response.Status,
+			"responseHeaders": Response.Header,
+			"responseBody":    responsebodyMap,
+			"processTime":     time.Now().UTC(),
+			"retries":         retries,
+			"timings":         callTimingOf(Response).report(),
+		}
+
+		if Response.StatusCode == http.StatusOK || Response.StatusCode == http.StatusNoContent {
+
+			if vGeneral.Prometheus_enabled == 1 {
+				m.api_pmnt_duration.With(prometheus.Labels{
+					"hostname":       vGeneral.Hostname,
+					"msg_type":       t_RequestToPay["eventType"].(string),
+					"service":        vService,
+					"participant":    t_RequestToPay["tenantId"].(string),
+					"direction":      "outbound",
+					"payment_method": t_RequestToPay["localInstrument"].(string),
+					"score":          "0.0"}).Observe(apiEnd)
+
+			}
+
+		} else {
+
+			tRequestToPayBody["responseResult"] = "FAILED POST"
+			tRequestToPayBody["responseBody"] = string(jsonDataResponsebody)
+
+			if vGeneral.Prometheus_enabled == 1 {
+				m.err_pmnt_processed.With(prometheus.Labels{
+					"hostname":       vGeneral.Hostname,
+					"msg_type":       t_RequestToPay["eventType"].(string),
+					"service":        vService,
+					"participant":    t_RequestToPay["tenantId"].(string),
+					"direction":      "outbound",
+					"payment_method": t_RequestToPay["localInstrument"].(string)}).Inc()
+
+			}
+
+			if vGeneral.Debuglevel > 2 {
+				grpcLog.Infoln("RequestToPay response Body    :", string(jsonDataResponsebody))
+				grpcLog.Infoln("RequestToPay response Result  : FAILED POST")
+
+			}
+
+			writeDeadLetter(t_RequestToPay, Response, jsonDataResponsebody, retries, nil)
+		}
+
+		writeResultLine(t_RequestToPay, time.Duration(apiEnd*float64(time.Second)), tRequestToPayBody, nil)
+	}
+
+	TransactionId := t_RequestToPay["transactionId"]
+	TagId := t_RequestToPay["eventId"]
+
+	if vGeneral.Json_to_file == 1 {
+
+		loc := fmt.Sprintf("%s%s%s_%s-%s.json", vGeneral.Output_path, pathSep, reccount, TransactionId, TagId)
+		if vGeneral.Debuglevel > 1 {
+			grpcLog.Infoln("RequestToPay Output Event     :", loc)
+
+		}
+
+		fd, err := json.MarshalIndent(t_RequestToPay, "", " ")
+		if err != nil {
+			grpcLog.Errorln("MarshalIndent error", err)
+
+		}
+
+		err = os.WriteFile(loc, fd, 0644)
+		if err != nil {
+			grpcLog.Errorln("os.WriteFile error", err)
+
+		}
+	}
+
+	if vGeneral.Call_fs_api == 1 && vGeneral.EngineResponse_to_file == 1 {
+
+		loc := fmt.Sprintf("%s%s%s_%s-%s-out.json", vGeneral.Output_path, pathSep, reccount, TransactionId, TagId)
+		if vGeneral.Debuglevel > 1 {
+			grpcLog.Infoln("RequestToPay engineResponse   :", loc)
+
+		}
+
+		fj, err := json.MarshalIndent(tRequestToPayBody, "", " ")
+		if err != nil {
+			grpcLog.Errorln("MarshalIndent error", err)
+
+		}
+
+		err = os.WriteFile(loc, fj, 0644)
+		if err != nil {
+			grpcLog.Errorln("os.WriteFile error", err)
+
+		}
+	}
+
+	return retries, deadLettered, nil
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"cmd/types"
+)
+
+// The split of 100 rolls into accepted/rejected/expired.
+func TestRtpOutcome(t *testing.T) {
+
+	tests := []struct {
+		name         string
+		acceptRate   int
+		rejectRate   int
+		wantAccepted int
+		wantRejected int
+		wantExpired  int
+	}{
+		{name: "not configured, all accepted", wantAccepted: 100},
+		{name: "split", acceptRate: 70, rejectRate: 20, wantAccepted: 70, wantRejected: 20, wantExpired: 10},
+		{name: "all accepted", acceptRate: 100, wantAccepted: 100},
+		{name: "all rejected", rejectRate: 100, wantRejected: 100},
+		{name: "accepted or expired", acceptRate: 40, wantAccepted: 40, wantExpired: 60},
+		{name: "rejected or expired", rejectRate: 25, wantRejected: 25, wantExpired: 75},
+		{name: "sum above 100, reject cut short", acceptRate: 80, rejectRate: 50, wantAccepted: 80, wantRejected: 20},
+		{name: "accept above 100", acceptRate: 150, rejectRate: 10, wantAccepted: 100},
+	}
+
+	defer func(saved types.Tp_general) { vGeneral = saved }(vGeneral)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vGeneral = types.Tp_general{RtpAcceptRate: tt.acceptRate, RtpRejectRate: tt.rejectRate}
+
+			counts := make(map[string]int)
+			for n := 0; n < 100; n++ {
+				counts[rtpOutcomeOf(n)]++
+			}
+
+			if counts[rtpAccepted] != tt.wantAccepted || counts[rtpRejected] != tt.wantRejected || counts[rtpExpired] != tt.wantExpired {
+				t.Errorf("rtpOutcomeOf() split %v, want ACCP %d RJCT %d EXPD %d", counts, tt.wantAccepted, tt.wantRejected, tt.wantExpired)
+			}
+		})
+	}
+}
+
+func TestRequestToPayResponse(t *testing.T) {
+
+	defer func(saved types.Tp_general) { vGeneral = saved }(vGeneral)
+	vGeneral = types.Tp_general{RtpExpiryMinutes: 60}
+
+	t_InboundPayment := map[string]interface{}{"transactionId": "t1", "tenantId": "creditorBank", "fromId": "debtor", "toId": "creditor",
+		"localInstrument": "RTP-PBPX", "eventTime": "2020-01-01T10:00:00", "amount": 100}
+	t_OutboundPayment := map[string]interface{}{"transactionId": "t1", "tenantId": "debtorBank"}
+
+	t_RequestToPay := constructRequestToPay(t_OutboundPayment, t_InboundPayment)
+
+	tests := []struct {
+		outcome    string
+		wantTenant string
+		wantFrom   string
+		wantTo     string
+		wantReason bool
+		wantExpiry bool
+	}{
+		{outcome: rtpAccepted, wantTenant: "debtorBank", wantFrom: "debtor", wantTo: "creditor"},
+		{outcome: rtpRejected, wantTenant: "debtorBank", wantFrom: "debtor", wantTo: "creditor", wantReason: true},
+		{outcome: rtpExpired, wantTenant: "creditorBank", wantFrom: "creditor", wantTo: "debtor", wantReason: true, wantExpiry: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.outcome, func(t *testing.T) {
+			before := time.Now().Add(-time.Second)
+
+			response := constructRequestToPayResponse(t_RequestToPay, t_OutboundPayment, tt.outcome)
+
+			if response["msgStatus"] != tt.outcome || response["eventType"] != requestToPayResponseEventType {
+				t.Errorf("msgStatus %v eventType %v, want %s %s", response["msgStatus"], response["eventType"], tt.outcome, requestToPayResponseEventType)
+			}
+			if response["tenantId"] != tt.wantTenant || response["fromId"] != tt.wantFrom || response["toId"] != tt.wantTo {
+				t.Errorf("tenantId %v fromId %v toId %v, want %s %s %s", response["tenantId"], response["fromId"], response["toId"], tt.wantTenant, tt.wantFrom, tt.wantTo)
+			}
+			if response["requestToPayReference"] != t_RequestToPay["requestToPayReference"] || response["requestToPayReference"] != t_InboundPayment["requestToPayReference"] {
+				t.Errorf("requestToPayReference %v, want %v", response["requestToPayReference"], t_RequestToPay["requestToPayReference"])
+			}
+			if _, ok := response["msgStatusReason"]; ok != tt.wantReason {
+				t.Errorf("msgStatusReason %v, want present %v", response["msgStatusReason"], tt.wantReason)
+			}
+			if response["expiryDateTime"] != nil != tt.wantExpiry || (tt.wantExpiry && response["expiryDateTime"] != t_RequestToPay["expiryDateTime"]) {
+				t.Errorf("expiryDateTime %v, want %v", response["expiryDateTime"], tt.wantExpiry)
+			}
+
+			// Posted now, also when expired, not at the (future) expiryDateTime
+			eventTime, err := time.ParseInLocation("2006-01-02T15:04:05", response["eventTime"].(string), time.Local)
+			if err != nil || eventTime.Before(before) || eventTime.After(time.Now()) {
+				t.Errorf("eventTime %v, want now", response["eventTime"])
+			}
+		})
+	}
+}
+
+// The events the request-to-pay phase posts, and whether the payment pair follows.
+func TestProcessRequestToPay(t *testing.T) {
+
+	tests := []struct {
+		name         string
+		acceptRate   int
+		rejectRate   int
+		wantAccepted bool
+		wantStatus   string
+	}{
+		{name: "defaults accept", wantAccepted: true, wantStatus: rtpAccepted},
+		{name: "accepted", acceptRate: 100, wantAccepted: true, wantStatus: rtpAccepted},
+		{name: "rejected", rejectRate: 100, wantStatus: rtpRejected},
+	}
+
+	defer func(saved types.Tp_general) { vGeneral = saved }(vGeneral)
+	defer func(saved Sink) { vSink = saved }(vSink)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vGeneral = types.Tp_general{Call_fs_api: 1, RtpAcceptRate: tt.acceptRate, RtpRejectRate: tt.rejectRate}
+			sink := &memorySink{}
+			vSink = sink
+
+			t_InboundPayment := map[string]interface{}{"transactionId": "t1", "tenantId": "creditorBank", "localInstrument": "RTP-PBAC", "eventTime": "2020-01-01T10:00:00"}
+			t_OutboundPayment := map[string]interface{}{"transactionId": "t1", "tenantId": "debtorBank", "localInstrument": "RTP-PBAC", "eventTime": "2020-01-01T10:00:00"}
+			t_RequestToPay := constructRequestToPay(t_OutboundPayment, t_InboundPayment)
+
+			accepted, err := processRequestToPay(t_RequestToPay, t_OutboundPayment, t_InboundPayment, "1", "rpp", 0)
+			if err != nil || accepted != tt.wantAccepted {
+				t.Fatalf("processRequestToPay() = %v, %v, want %v", accepted, err, tt.wantAccepted)
+			}
+
+			events := sink.Events()
+			if len(events) != 2 || events[0].EventType != requestToPayEventType || events[1].EventType != requestToPayResponseEventType {
+				t.Fatalf("events posted %+v, want requestToPay, requestToPayResponse", events)
+			}
+			var response map[string]interface{}
+			if err = json.Unmarshal(events[1].Bytes, &response); err != nil || response["msgStatus"] != tt.wantStatus {
+				t.Errorf("requestToPayResponse msgStatus %v (%v), want %s", response["msgStatus"], err, tt.wantStatus)
+			}
+
+			// Accepted, the payments are dated after the acceptance
+			if moved := t_InboundPayment["eventTime"] != "2020-01-01T10:00:00"; moved != tt.wantAccepted {
+				t.Errorf("payment eventTime %v, moved %v want %v", t_InboundPayment["eventTime"], moved, tt.wantAccepted)
+			}
+		})
+	}
+}
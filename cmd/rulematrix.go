@@ -0,0 +1,261 @@
+/*****************************************************************************
+*
+*	File			: rulematrix.go
+*
+* 	Created			: 19 Oct 2026
+*
+*	Description		: Rule hit matrix, which rules fired for which transaction / scenario file, built from the
+*					: engineResponse outcomes (see engineresponse.go) and written at the end of the run.
+*
+*					: A row per scenario step, the scenario (input) file when json_from_file = 1 (a soak run cycling
+*					: through the files adds up per file), else the record number (the first maxRecordSteps records),
+*					: a column per ruleId:
+*					:	A	=> fired and alerted (alert and not suppressAlert)
+*					:	F	=> fired
+*					: followed by totals per rule (rows fired/alerted) and per tenant.
+*
+*					: ruleMatrix_file "rule_matrix" writes
+*					:	rule_matrix.csv			the matrix, plus TOTAL FIRED / TOTAL ALERTED rows
+*					:	rule_matrix_tenants.csv	per tenant, rows and rows fired/alerted per rule
+*					:	rule_matrix.md			all of the above as Markdown tables
+*
+*	By				: George Leonard (georgelza@gmail.com)
+*
+*****************************************************************************/
+
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	ruleFired   = "F"
+	ruleAlerted = "A"
+)
+
+type ruleMatrixRow struct {
+	Step          string
+	TransactionId string // last transaction seen for the step
+	Tenant        string
+	Txns          int               // transactions seen for the step
+	Rules         map[string]string // ruleId => F or A
+}
+
+var (
+	ruleMatrixMu      sync.Mutex
+	vRuleMatrix       = make(map[string]*ruleMatrixRow)
+	vRuleIds          = make(map[string]bool)
+	vRuleMatrixCapped bool
+)
+
+// Add a engineResponse outcome to the matrix.
+func recordRuleHits(t_Payload map[string]interface{}, outcome *engineOutcome) {
+
+	if (vGeneral.RuleMatrix_file == "" && vGeneral.Report_file == "") || outcome == nil {
+		return
+	}
+
+	_, step := cassetteKey(t_Payload)
+	transactionId := fmt.Sprintf("%v", t_Payload["transactionId"])
+
+	ruleMatrixMu.Lock()
+	defer ruleMatrixMu.Unlock()
+
+	row, ok := vRuleMatrix[step]
+	if !ok {
+		// Same bound as the outcomes, see maxRecordSteps
+		if vGeneral.Json_from_file != 1 && len(vRuleMatrix) >= maxRecordSteps {
+			if !vRuleMatrixCapped {
+				vRuleMatrixCapped = true
+				grpcLog.Warningln("Rule matrix capped at         :", maxRecordSteps, "record steps, later records not kept")
+			}
+			return
+		}
+		row = &ruleMatrixRow{Step: step, Tenant: fmt.Sprintf("%v", t_Payload["tenantId"]), Rules: make(map[string]string)}
+		vRuleMatrix[step] = row
+	}
+	// A transaction's scored events follow each other, a change of transactionId is the next transaction
+	if row.TransactionId != transactionId {
+		row.TransactionId = transactionId
+		row.Txns++
+	}
+
+	for _, ruleId := range outcome.TriggeredRules {
+		vRuleIds[ruleId] = true
+		if row.Rules[ruleId] == "" {
+			row.Rules[ruleId] = ruleFired
+		}
+	}
+	for _, ruleId := range outcome.AlertedRules {
+		vRuleIds[ruleId] = true
+		row.Rules[ruleId] = ruleAlerted
+	}
+}
+
+// Record numbers in numeric order, file names alphabetically.
+func stepLess(a string, b string) bool {
+
+	na, errA := strconv.Atoi(a)
+	nb, errB := strconv.Atoi(b)
+	if errA == nil && errB == nil {
+		return na < nb
+	}
+
+	return a < b
+}
+
+// The matrix, totals and tenant tables, each as header + rows.
+func ruleMatrixTables() (matrix [][]string, tenants [][]string) {
+
+	ruleMatrixMu.Lock()
+	defer ruleMatrixMu.Unlock()
+
+	ruleIds := sortedKeys(vRuleIds)
+
+	var steps []string
+	for step := range vRuleMatrix {
+		steps = append(steps, step)
+	}
+	sort.Slice(steps, func(i, j int) bool { return stepLess(steps[i], steps[j]) })
+
+	matrix = append(matrix, append([]string{"step", "transactionId", "tenantId", "txns"}, ruleIds...))
+
+	fired := make(map[string]int)
+	alerted := make(map[string]int)
+
+	type tenantTotals struct {
+		rows    int
+		fired   map[string]int
+		alerted map[string]int
+	}
+	perTenant := make(map[string]*tenantTotals)
+
+	for _, step := range steps {
+		row := vRuleMatrix[step]
+
+		totals, ok := perTenant[row.Tenant]
+		if !ok {
+			totals = &tenantTotals{fired: make(map[string]int), alerted: make(map[string]int)}
+			perTenant[row.Tenant] = totals
+		}
+		totals.rows++
+
+		line := []string{row.Step, row.TransactionId, row.Tenant, strconv.Itoa(row.Txns)}
+		for _, ruleId := range ruleIds {
+			hit := row.Rules[ruleId]
+			line = append(line, hit)
+
+			if hit != "" {
+				fired[ruleId]++
+				totals.fired[ruleId]++
+			}
+			if hit == ruleAlerted {
+				alerted[ruleId]++
+				totals.alerted[ruleId]++
+			}
+		}
+		matrix = append(matrix, line)
+	}
+
+	firedLine := []string{"TOTAL FIRED", "", "", ""}
+	alertedLine := []string{"TOTAL ALERTED", "", "", ""}
+	for _, ruleId := range ruleIds {
+		firedLine = append(firedLine, strconv.Itoa(fired[ruleId]))
+		alertedLine = append(alertedLine, strconv.Itoa(alerted[ruleId]))
+	}
+	matrix = append(matrix, firedLine, alertedLine)
+
+	// Per tenant, "fired/alerted" per rule
+	var tenantIds []string
+	for tenantId := range perTenant {
+		tenantIds = append(tenantIds, tenantId)
+	}
+	sort.Strings(tenantIds)
+
+	tenants = append(tenants, append([]string{"tenantId", "rows"}, ruleIds...))
+	for _, tenantId := range tenantIds {
+		totals := perTenant[tenantId]
+
+		line := []string{tenantId, strconv.Itoa(totals.rows)}
+		for _, ruleId := range ruleIds {
+			line = append(line, fmt.Sprintf("%d/%d", totals.fired[ruleId], totals.alerted[ruleId]))
+		}
+		tenants = append(tenants, line)
+	}
+
+	return matrix, tenants
+}
+
+func writeCSV(filename string, table [][]string) error {
+
+	var buf bytes.Buffer
+
+	w := csv.NewWriter(&buf)
+	if err := w.WriteAll(table); err != nil {
+		return err
+	}
+
+	return os.WriteFile(filename, buf.Bytes(), 0644)
+}
+
+func markdownTable(buf *bytes.Buffer, table [][]string) {
+
+	escape := func(cell string) string { return strings.ReplaceAll(cell, "|", "\\|") }
+
+	for i, line := range table {
+		cells := make([]string, len(line))
+		for j, cell := range line {
+			cells[j] = escape(cell)
+		}
+		fmt.Fprintf(buf, "| %s |\n", strings.Join(cells, " | "))
+
+		if i == 0 {
+			fmt.Fprintf(buf, "|%s\n", strings.Repeat(" --- |", len(line)))
+		}
+	}
+	buf.WriteString("\n")
+}
+
+// Write the CSV and Markdown reports, at the end of the run.
+func reportRuleMatrix() {
+
+	if vGeneral.RuleMatrix_file == "" {
+		return
+	}
+
+	matrix, tenants := ruleMatrixTables()
+
+	base := strings.TrimSuffix(vGeneral.RuleMatrix_file, ".csv")
+
+	if err := writeCSV(base+".csv", matrix); err != nil {
+		grpcLog.Errorln("Rule matrix write error", err)
+
+	}
+	if err := writeCSV(base+"_tenants.csv", tenants); err != nil {
+		grpcLog.Errorln("Rule matrix write error", err)
+
+	}
+
+	var md bytes.Buffer
+	md.WriteString("# Rule hit matrix\n\n")
+	md.WriteString("A = fired and alerted, F = fired\n\n")
+	markdownTable(&md, matrix)
+	md.WriteString("## Per tenant\n\n")
+	md.WriteString("rows fired/alerted\n\n")
+	markdownTable(&md, tenants)
+
+	if err := os.WriteFile(base+".md", md.Bytes(), 0644); err != nil {
+		grpcLog.Errorln("Rule matrix write error", err)
+
+	}
+
+	grpcLog.Infoln("Rule matrix written to        :", base+".csv", base+".md")
+}
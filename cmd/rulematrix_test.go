@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"cmd/types"
+)
+
+func TestRecordRuleHits(t *testing.T) {
+
+	type hit struct {
+		step          string
+		transactionId string
+		triggered     []string
+		alerted       []string
+	}
+
+	tests := []struct {
+		name    string
+		general types.Tp_general
+		hits    []hit
+		want    [][]string
+	}{
+		{
+			name: "off",
+			hits: []hit{{"1", "t1", []string{"R1"}, nil}},
+		},
+		{
+			name:    "record steps",
+			general: types.Tp_general{RuleMatrix_file: "rule_matrix"},
+			hits:    []hit{{"2", "t2", []string{"R1", "R2"}, []string{"R2"}}, {"1", "t1", []string{"R1"}, nil}},
+			want: [][]string{
+				{"step", "transactionId", "tenantId", "txns", "R1", "R2"},
+				{"1", "t1", "tn1", "1", "F", ""},
+				{"2", "t2", "tn1", "1", "F", "A"},
+				{"TOTAL FIRED", "", "", "", "2", "1"},
+				{"TOTAL ALERTED", "", "", "", "0", "1"},
+			},
+		},
+		{
+			name:    "scenario file cycled, both RT events of a transaction",
+			general: types.Tp_general{Report_file: "report.html", Json_from_file: 1},
+			hits: []hit{
+				{"a.json", "t1", []string{"R1"}, nil}, {"a.json", "t1", []string{"R2"}, nil},
+				{"a.json", "t2", []string{"R1"}, []string{"R1"}},
+				{"a.json", "t3", nil, nil},
+			},
+			want: [][]string{
+				{"step", "transactionId", "tenantId", "txns", "R1", "R2"},
+				{"a.json", "t3", "tn1", "3", "A", "F"},
+				{"TOTAL FIRED", "", "", "", "1", "1"},
+				{"TOTAL ALERTED", "", "", "", "1", "0"},
+			},
+		},
+	}
+
+	defer func(saved types.Tp_general) { vGeneral = saved }(vGeneral)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vGeneral = tt.general
+			vRuleMatrix = make(map[string]*ruleMatrixRow)
+			vRuleIds = make(map[string]bool)
+
+			for _, h := range tt.hits {
+				setScenarioStep(h.transactionId, h.step)
+				recordRuleHits(map[string]interface{}{"transactionId": h.transactionId, "tenantId": "tn1"}, &engineOutcome{TriggeredRules: h.triggered, AlertedRules: h.alerted})
+				clearScenarioStep(h.transactionId)
+			}
+
+			if len(tt.want) == 0 {
+				if len(vRuleMatrix) != 0 {
+					t.Errorf("rows recorded = %d, want none", len(vRuleMatrix))
+				}
+				return
+			}
+			if matrix, _ := ruleMatrixTables(); !reflect.DeepEqual(matrix, tt.want) {
+				t.Errorf("ruleMatrixTables() = %q, want %q", matrix, tt.want)
+			}
+		})
+	}
+}
+
+// A soak run on fake data, a step per record, keeps the first maxRecordSteps rows.
+func TestRecordRuleHitsCapped(t *testing.T) {
+
+	defer func(saved types.Tp_general) { vGeneral = saved }(vGeneral)
+	vGeneral = types.Tp_general{RuleMatrix_file: "rule_matrix"}
+	vRuleMatrix = make(map[string]*ruleMatrixRow)
+	vRuleIds = make(map[string]bool)
+	vRuleMatrixCapped = false
+
+	for i := 0; i < maxRecordSteps+10; i++ {
+		transactionId := fmt.Sprintf("t%d", i)
+		setScenarioStep(transactionId, strconv.Itoa(i))
+		recordRuleHits(map[string]interface{}{"transactionId": transactionId}, &engineOutcome{TriggeredRules: []string{"R1"}})
+		clearScenarioStep(transactionId)
+	}
+
+	if len(vRuleMatrix) != maxRecordSteps || !vRuleMatrixCapped {
+		t.Errorf("rows = %d capped %v, want %d capped", len(vRuleMatrix), vRuleMatrixCapped, maxRecordSteps)
+	}
+	vRuleMatrix = make(map[string]*ruleMatrixRow)
+}
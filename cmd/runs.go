@@ -0,0 +1,396 @@
+/*****************************************************************************
+*
+*	File			: runs.go
+*
+* 	Created			: 19 Oct 2026
+*
+*	Description		: Per-run output directories. With runs_path set every run writes into it's own
+*					: <runs_path>/<timestamp>-<runId>/ directory instead of output_path, the event (json_to_file) and
+*					: -out.json (engineResponse_to_file) files, results_file, ruleMatrix_file, report_file and
+*					: resultsLog_file (their file names, in the run directory), plus a manifest.json:
+*					:	- runId, env, args, tool version (module version and vcs revision) and host
+*					:	- start and end times, and the run's totals
+*					:	- the effective config, secrets redacted
+*					:	- sha256 of the seed file, the config file and every input (scenario) file
+*
+*					: The manifest is written when the run starts and re-written when it ends, a run that never
+*					: finished (crashed/killed) has no ended time.
+*
+*					: Only directories named <timestamp>-<runId> holding a manifest.json are runs, anything else in
+*					: runs_path is never listed, inspected or pruned.
+*
+*					: fs_producer runs <env> [list]				the runs, oldest first
+*					: fs_producer runs <env> inspect <run>		the manifest and files of a run, <run> is the directory
+*					:										name, or a unique part of it (e.g. the runId)
+*					: fs_producer runs <env> prune [keep]		remove all but the newest keep (default runsKeep) runs
+*
+*	By				: George Leonard (georgelza@gmail.com)
+*
+*****************************************************************************/
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	runManifestFile = "manifest.json"
+	runTimeFormat   = "20060102-150405"
+)
+
+type runTotals struct {
+	Txns        int64   `json:"txns"`
+	Events      int64   `json:"events"`
+	Retries     int64   `json:"retries"`
+	Failed      int64   `json:"failed"`
+	Elapsed     float64 `json:"elapsedSeconds"`
+	Interrupted string  `json:"interrupted,omitempty"`
+}
+
+type runManifest struct {
+	RunId      string                 `json:"runId"`
+	Env        string                 `json:"env"`
+	Args       []string               `json:"args"`
+	Version    string                 `json:"version"`
+	Host       string                 `json:"host"`
+	OSName     string                 `json:"osName"`
+	Started    time.Time              `json:"started"`
+	Ended      *time.Time             `json:"ended,omitempty"`
+	Totals     *runTotals             `json:"totals,omitempty"`
+	ConfigFile string                 `json:"configFile"`
+	ConfigHash string                 `json:"configHash"`
+	SeedFile   string                 `json:"seedFile"`
+	SeedHash   string                 `json:"seedHash"`
+	Inputs     map[string]string      `json:"inputs,omitempty"` // input (scenario) file => sha256
+	Config     map[string]interface{} `json:"config"`
+}
+
+var (
+	vRunDir      string
+	vRunManifest *runManifest
+)
+
+// <runTimeFormat>-<runId>, as named by startRun
+var runDirPattern = regexp.MustCompile(`^[0-9]{8}-[0-9]{6}-[0-9a-f]{8}$`)
+
+// The module version, plus the vcs revision when built from a git checkout.
+func toolVersion() string {
+
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+
+	version := info.Main.Version
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			version += " " + setting.Value
+		case "vcs.modified":
+			if setting.Value == "true" {
+				version += " (modified)"
+			}
+		}
+	}
+
+	return version
+}
+
+func fileHash(filename string) string {
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return ""
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// A per-run output file, in the run directory.
+func inRunDir(filename string) string {
+
+	if filename == "" {
+		return ""
+	}
+
+	return filepath.Join(vRunDir, filepath.Base(filename))
+}
+
+// Create the run directory and point the run's output files into it, returnedRecs are the input files (json_from_file).
+func startRun(env string, start time.Time, returnedRecs map[int]string) {
+
+	if vGeneral.Runs_path == "" {
+		return
+	}
+
+	runId := strings.Split(uuid.New().String(), "-")[0]
+	vRunDir = filepath.Join(vGeneral.Runs_path, fmt.Sprintf("%s-%s", start.UTC().Format(runTimeFormat), runId))
+
+	if err := os.MkdirAll(vRunDir, 0755); err != nil {
+		grpcLog.Fatalln("Problem creating run directory: ", err)
+
+	}
+
+	vGeneral.Output_path = vRunDir
+	vGeneral.Results_file = inRunDir(vGeneral.Results_file)
+	vGeneral.RuleMatrix_file = inRunDir(vGeneral.RuleMatrix_file)
+	vGeneral.Report_file = inRunDir(vGeneral.Report_file)
+	vGeneral.ResultsLog_file = inRunDir(vGeneral.ResultsLog_file)
+
+	configFile := fmt.Sprintf("%s%s%s_app.json", vGeneral.CurrentPath, pathSep, env)
+
+	vRunManifest = &runManifest{
+		RunId:      runId,
+		Env:        env,
+		Args:       os.Args[1:],
+		Version:    toolVersion(),
+		Host:       vGeneral.Hostname,
+		OSName:     vGeneral.OSName,
+		Started:    start,
+		ConfigFile: configFile,
+		ConfigHash: fileHash(configFile),
+		SeedFile:   vGeneral.SeedFile,
+		SeedHash:   fileHash(vGeneral.SeedFile),
+		Config:     redactedConfig(),
+	}
+
+	if len(returnedRecs) > 0 {
+		vRunManifest.Inputs = make(map[string]string)
+		for _, name := range returnedRecs {
+			vRunManifest.Inputs[name] = fileHash(filepath.Join(vGeneral.Input_path, name))
+		}
+	}
+
+	writeRunManifest()
+
+	grpcLog.Infoln("Run directory                 :", vRunDir)
+}
+
+// Record the end time and totals.
+func endRun(start time.Time, end time.Time) {
+
+	if vRunManifest == nil {
+		return
+	}
+
+	vRunManifest.Ended = &end
+	vRunManifest.Totals = &runTotals{
+		Txns:    atomic.LoadInt64(&vStats.txns),
+		Events:  atomic.LoadInt64(&vStats.events),
+		Retries: atomic.LoadInt64(&vStats.retries),
+		Failed:  atomic.LoadInt64(&vStats.failed),
+		Elapsed: end.Sub(start).Seconds(),
+	}
+	if stopping() {
+		vRunManifest.Totals.Interrupted = stopReason()
+	}
+
+	writeRunManifest()
+}
+
+func writeRunManifest() {
+
+	fj, err := json.MarshalIndent(vRunManifest, "", " ")
+	if err == nil {
+		err = os.WriteFile(filepath.Join(vRunDir, runManifestFile), fj, 0644)
+	}
+	if err != nil {
+		grpcLog.Errorln("Run manifest write error", err)
+
+	}
+}
+
+// The run directories in runs_path, oldest first (the names start with the UTC start time).
+func listRuns() ([]string, error) {
+
+	entries, err := os.ReadDir(vGeneral.Runs_path)
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []string
+	for _, entry := range entries {
+		if isRunDir(entry) {
+			runs = append(runs, entry.Name())
+		}
+	}
+	sort.Strings(runs)
+
+	return runs, nil
+}
+
+// Named as by startRun, and holding a manifest, so prune can never remove anything but runs.
+func isRunDir(entry os.DirEntry) bool {
+
+	if !entry.IsDir() || !runDirPattern.MatchString(entry.Name()) {
+		return false
+	}
+
+	info, err := os.Stat(filepath.Join(vGeneral.Runs_path, entry.Name(), runManifestFile))
+
+	return err == nil && info.Mode().IsRegular()
+}
+
+func readRunManifest(run string) (*runManifest, error) {
+
+	content, err := os.ReadFile(filepath.Join(vGeneral.Runs_path, run, runManifestFile))
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest runManifest
+	if err = json.Unmarshal(content, &manifest); err != nil {
+		return nil, err
+	}
+
+	return &manifest, nil
+}
+
+// A run by directory name, or a unique part of it.
+func findRun(runs []string, name string) (string, error) {
+
+	var found []string
+	for _, run := range runs {
+		if run == name {
+			return run, nil
+		}
+		if strings.Contains(run, name) {
+			found = append(found, run)
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return "", fmt.Errorf("no run matches %s", name)
+	case 1:
+		return found[0], nil
+	}
+
+	return "", fmt.Errorf("%s matches %d runs: %s", name, len(found), strings.Join(found, ", "))
+}
+
+// fs_producer runs <env> [list | inspect <run> | prune [keep]]
+func runRuns(args []string) {
+
+	if len(args) < 1 {
+		grpcLog.Fatalln("Usage: fs_producer runs <env> [list | inspect <run> | prune [keep]]")
+
+	}
+
+	vGeneral = loadConfig(args[0])
+	if vGeneral.Runs_path == "" {
+		grpcLog.Fatalln("runs_path is not set")
+
+	}
+
+	runs, err := listRuns()
+	if err != nil {
+		grpcLog.Fatalln("Problem reading runs_path: ", err)
+
+	}
+
+	command := "list"
+	if len(args) > 1 {
+		command = args[1]
+	}
+
+	switch command {
+	case "list":
+		for _, run := range runs {
+			manifest, err := readRunManifest(run)
+			if err != nil {
+				grpcLog.Infof("%-30s : no manifest, %s", run, err)
+				continue
+			}
+
+			status := "incomplete"
+			if manifest.Totals != nil {
+				status = fmt.Sprintf("%.1fs txns %d events %d failed %d", manifest.Totals.Elapsed, manifest.Totals.Txns, manifest.Totals.Events, manifest.Totals.Failed)
+				if manifest.Totals.Interrupted != "" {
+					status += ", interrupted by " + manifest.Totals.Interrupted
+				}
+			}
+			grpcLog.Infof("%-30s : %s %s, %s", run, manifest.Env, manifest.Host, status)
+		}
+		grpcLog.Infoln("Runs                          : ", len(runs))
+
+	case "inspect":
+		if len(args) < 3 {
+			grpcLog.Fatalln("Usage: fs_producer runs <env> inspect <run>")
+
+		}
+		run, err := findRun(runs, args[2])
+		if err != nil {
+			grpcLog.Fatalln(err)
+
+		}
+
+		content, err := os.ReadFile(filepath.Join(vGeneral.Runs_path, run, runManifestFile))
+		if err != nil {
+			grpcLog.Fatalln("Problem reading run manifest: ", err)
+
+		}
+		fmt.Println(string(content))
+
+		var files, size int64
+		filepath.Walk(filepath.Join(vGeneral.Runs_path, run), func(name string, info os.FileInfo, err error) error {
+			if err == nil && !info.IsDir() {
+				files++
+				size += info.Size()
+			}
+			return nil
+		})
+		grpcLog.Infoln("Run directory                 :", filepath.Join(vGeneral.Runs_path, run))
+		grpcLog.Infoln("Files                         : ", files, "files,", size, "bytes")
+
+	case "prune":
+		keep := vGeneral.RunsKeep
+		if keep == 0 {
+			keep = 10
+		}
+		if len(args) > 2 {
+			if keep, err = strconv.Atoi(args[2]); err != nil || keep < 0 {
+				grpcLog.Fatalln("Usage: fs_producer runs <env> prune [keep]")
+
+			}
+		}
+
+		var pruned int
+		for i := 0; i < len(runs)-keep; i++ {
+			if err := os.RemoveAll(filepath.Join(vGeneral.Runs_path, runs[i])); err != nil {
+				grpcLog.Errorln("Problem removing run", runs[i], err)
+				continue
+
+			}
+			pruned++
+			grpcLog.Infoln("Pruned                        :", runs[i])
+		}
+		grpcLog.Infoln("Runs Pruned / Kept            : ", pruned, "/", len(runs)-pruned)
+
+	default:
+		grpcLog.Fatalln("Usage: fs_producer runs <env> [list | inspect <run> | prune [keep]]")
+
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"cmd/types"
+)
+
+// Only <timestamp>-<runId> directories with a manifest are runs, prune removes from this list only.
+func TestListRuns(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		dir      bool
+		manifest bool
+		want     bool
+	}{
+		{name: "20261018-090000-0123abcd", dir: true, manifest: true, want: true},
+		{name: "20261019-123840-ae77193b", dir: true, manifest: true, want: true},
+		{name: "20261019-123841-deadbeef", dir: true},
+		{name: "20261019-123842-cafef00d", manifest: true},
+		{name: "20261019-123840-AE77193B", dir: true, manifest: true},
+		{name: "20261019-123840-ae77193b-copy", dir: true, manifest: true},
+		{name: "cmd", dir: true, manifest: true},
+		{name: ".git", dir: true},
+	}
+
+	defer func(saved types.Tp_general) { vGeneral = saved }(vGeneral)
+	vGeneral.Runs_path = t.TempDir()
+
+	var want []string
+	for _, tt := range tests {
+		path := filepath.Join(vGeneral.Runs_path, tt.name)
+		if tt.dir {
+			if err := os.Mkdir(path, 0755); err != nil {
+				t.Fatal(err)
+			}
+			if tt.manifest {
+				if err := os.WriteFile(filepath.Join(path, runManifestFile), []byte("{}"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+		} else if err := os.WriteFile(path, []byte("{}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if tt.want {
+			want = append(want, tt.name)
+		}
+	}
+
+	runs, err := listRuns()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(runs, want) {
+		t.Errorf("listRuns() = %v, want %v", runs, want)
+	}
+}
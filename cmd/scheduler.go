@@ -0,0 +1,182 @@
+/*****************************************************************************
+*
+*	File			: scheduler.go
+*
+* 	Created			: 19 Oct 2026
+*
+*	Description		: Open-loop rate control. Transactions are started as per a rate profile, independent of how long the
+*					: engine takes to respond, so engine saturation shows up as latency rather than as a lower rate.
+*
+*					: rateProfile
+*					:	constant	=> targetEPS for hold seconds (hold = 0, until testsize is reached)
+*					:	ramp		=> 0 -> targetEPS over rampUp, hold, targetEPS -> 0 over rampDown, linear
+*					:	step		=> as ramp, but in <steps> equal steps
+*					:	spike		=> as constant, with spikeEPS for spikeDuration seconds every spikeEvery seconds
+*
+*					: targetEPS/spikeEPS count 2 events per transaction (the payment/addPayee pair). RTP-* transactions
+*					: post 4 (requestToPay, requestToPayResponse and the payment pair), so with RTP-PBPX/RTP-PBAC the
+*					: achieved event rate runs up to twice targetEPS, see Events/Second in the run summary.
+*
+*					: When all workers are busy the scheduler falls behind, anything later than lagThreshold ms is
+*					: counted and reported, this is the tool being saturated, not the engine, add workers.
+*
+*	By				: George Leonard (georgelza@gmail.com)
+*
+*****************************************************************************/
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// The events a scheduled transaction counts as, also for RTP-* transactions, see above.
+const eventsPerTransaction = 2
+
+// Config check, without a positive targetEPS a constant profile never hands out a transaction and never completes.
+func checkSchedule() error {
+
+	if vGeneral.RateProfile != "" && vGeneral.TargetEPS <= 0 {
+		return fmt.Errorf("rateProfile %s requires targetEPS > 0, got %v", vGeneral.RateProfile, vGeneral.TargetEPS)
+	}
+
+	return nil
+}
+
+// The target events/second at elapsed into the run, done is true once the profile has completed.
+func targetRate(elapsed time.Duration) (eps float64, done bool) {
+
+	rampUp := time.Duration(vGeneral.RampUp) * time.Second
+	hold := time.Duration(vGeneral.Hold) * time.Second
+	rampDown := time.Duration(vGeneral.RampDown) * time.Second
+	target := vGeneral.TargetEPS
+
+	switch vGeneral.RateProfile {
+	case "constant", "spike":
+		if hold > 0 && elapsed >= hold {
+			return 0, true
+		}
+
+		if vGeneral.RateProfile == "spike" && vGeneral.SpikeEvery > 0 {
+			sinceSpike := elapsed % (time.Duration(vGeneral.SpikeEvery) * time.Second)
+			if sinceSpike < time.Duration(vGeneral.SpikeDuration)*time.Second {
+				return vGeneral.SpikeEPS, false
+			}
+		}
+
+		return target, false
+
+	case "ramp", "step":
+		var fraction float64
+
+		switch {
+		case elapsed < rampUp:
+			fraction = float64(elapsed) / float64(rampUp)
+
+		case elapsed < rampUp+hold:
+			fraction = 1
+
+		case elapsed < rampUp+hold+rampDown:
+			fraction = 1 - float64(elapsed-rampUp-hold)/float64(rampDown)
+
+		default:
+			return 0, true
+
+		}
+
+		if vGeneral.RateProfile == "step" && vGeneral.Steps > 0 {
+			steps := float64(vGeneral.Steps)
+			fraction = math.Ceil(fraction*steps) / steps
+		}
+
+		return target * fraction, false
+
+	}
+
+	grpcLog.Errorln("Unknown rateProfile", vGeneral.RateProfile, "running constant")
+	vGeneral.RateProfile = "constant"
+
+	return target, false
+}
+
+// Hand out transactions to the workers as per the profile, until the profile completes or todo_count is reached.
+func runSchedule(todo_count int, records chan<- txnJob) {
+
+	lagThreshold := time.Duration(vGeneral.LagThreshold) * time.Millisecond
+	if lagThreshold <= 0 {
+		lagThreshold = 100 * time.Millisecond
+	}
+
+	vStart := time.Now()
+	scheduled := vStart
+	lastWarning := time.Time{}
+	credit := 0.0
+
+	for count := 0; count < todo_count; count++ {
+
+		// Work out when the next transaction is due. We step through the profile in at most 10ms increments, accumulating
+		// transaction credit at the rate applicable at that point, so low rates (start of a ramp) don't jump ahead.
+		for credit < 1 {
+			eps, done := targetRate(scheduled.Sub(vStart))
+			if done {
+				return
+			}
+
+			step := 10 * time.Millisecond
+			if eps > 0 {
+				if interval := time.Duration(float64(time.Second) * (1 - credit) * eventsPerTransaction / eps); interval <= step {
+					scheduled = scheduled.Add(interval)
+					credit = 1
+					break
+				}
+			}
+
+			scheduled = scheduled.Add(step)
+			credit += eps / eventsPerTransaction * step.Seconds()
+		}
+		credit--
+
+		if wait := time.Until(scheduled); wait > 0 {
+			select {
+			case <-time.After(wait):
+			case <-vStop:
+				return
+
+			case <-vSoakEnd:
+				return
+
+			}
+		}
+
+		select {
+		case records <- txnJob{count: count, scheduled: scheduled}:
+		case <-vStop:
+			return
+
+		case <-vSoakEnd:
+			return
+
+		}
+
+		// How late did the transaction get to a worker, if the workers are all busy we fall behind the schedule.
+		lag := time.Since(scheduled)
+		if lag > lagThreshold {
+			atomic.AddInt64(&vStats.behind, 1)
+
+			if time.Since(lastWarning) > 5*time.Second {
+				grpcLog.Warningf("Falling behind schedule by    : %.3f Sec, all %d workers busy, consider more workers", lag.Seconds(), vGeneral.Workers)
+				lastWarning = time.Now()
+			}
+		}
+
+		for {
+			max := atomic.LoadInt64(&vStats.maxLag)
+			if int64(lag) <= max || atomic.CompareAndSwapInt64(&vStats.maxLag, max, int64(lag)) {
+				break
+			}
+		}
+	}
+}
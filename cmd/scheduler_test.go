@@ -0,0 +1,136 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"cmd/types"
+)
+
+func TestTargetRate(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		general  types.Tp_general
+		elapsed  time.Duration
+		wantEPS  float64
+		wantDone bool
+	}{
+		{
+			name:    "constant",
+			general: types.Tp_general{RateProfile: "constant", TargetEPS: 100, Hold: 60},
+			elapsed: 30 * time.Second,
+			wantEPS: 100,
+		},
+		{
+			name:     "constant hold done",
+			general:  types.Tp_general{RateProfile: "constant", TargetEPS: 100, Hold: 60},
+			elapsed:  60 * time.Second,
+			wantDone: true,
+		},
+		{
+			name:    "constant no hold",
+			general: types.Tp_general{RateProfile: "constant", TargetEPS: 100},
+			elapsed: 24 * time.Hour,
+			wantEPS: 100,
+		},
+		{
+			name:    "spike during spike",
+			general: types.Tp_general{RateProfile: "spike", TargetEPS: 100, SpikeEPS: 500, SpikeEvery: 60, SpikeDuration: 10},
+			elapsed: 65 * time.Second,
+			wantEPS: 500,
+		},
+		{
+			name:    "spike between spikes",
+			general: types.Tp_general{RateProfile: "spike", TargetEPS: 100, SpikeEPS: 500, SpikeEvery: 60, SpikeDuration: 10},
+			elapsed: 75 * time.Second,
+			wantEPS: 100,
+		},
+		{
+			name:    "ramp up",
+			general: types.Tp_general{RateProfile: "ramp", TargetEPS: 100, RampUp: 10, Hold: 10, RampDown: 10},
+			elapsed: 5 * time.Second,
+			wantEPS: 50,
+		},
+		{
+			name:    "ramp hold",
+			general: types.Tp_general{RateProfile: "ramp", TargetEPS: 100, RampUp: 10, Hold: 10, RampDown: 10},
+			elapsed: 15 * time.Second,
+			wantEPS: 100,
+		},
+		{
+			name:    "ramp down",
+			general: types.Tp_general{RateProfile: "ramp", TargetEPS: 100, RampUp: 10, Hold: 10, RampDown: 10},
+			elapsed: 25 * time.Second,
+			wantEPS: 50,
+		},
+		{
+			name:     "ramp done",
+			general:  types.Tp_general{RateProfile: "ramp", TargetEPS: 100, RampUp: 10, Hold: 10, RampDown: 10},
+			elapsed:  30 * time.Second,
+			wantDone: true,
+		},
+		{
+			name:    "step up",
+			general: types.Tp_general{RateProfile: "step", TargetEPS: 100, RampUp: 10, Hold: 10, RampDown: 10, Steps: 4},
+			elapsed: 3 * time.Second,
+			wantEPS: 50,
+		},
+		{
+			name:    "step down",
+			general: types.Tp_general{RateProfile: "step", TargetEPS: 100, RampUp: 10, Hold: 10, RampDown: 10, Steps: 4},
+			elapsed: 28 * time.Second,
+			wantEPS: 25,
+		},
+		{
+			name:    "unknown runs constant",
+			general: types.Tp_general{RateProfile: "sawtooth", TargetEPS: 100},
+			elapsed: 5 * time.Second,
+			wantEPS: 100,
+		},
+	}
+
+	defer func(saved types.Tp_general) { vGeneral = saved }(vGeneral)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vGeneral = tt.general
+
+			eps, done := targetRate(tt.elapsed)
+			if done != tt.wantDone {
+				t.Fatalf("targetRate(%v) done = %v, want %v", tt.elapsed, done, tt.wantDone)
+			}
+			if !done && math.Abs(eps-tt.wantEPS) > 1e-9 {
+				t.Errorf("targetRate(%v) = %v, want %v", tt.elapsed, eps, tt.wantEPS)
+			}
+		})
+	}
+}
+
+func TestCheckSchedule(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		general types.Tp_general
+		wantErr bool
+	}{
+		{name: "closed loop", general: types.Tp_general{}},
+		{name: "constant", general: types.Tp_general{RateProfile: "constant", TargetEPS: 100}},
+		{name: "constant no targetEPS", general: types.Tp_general{RateProfile: "constant"}, wantErr: true},
+		{name: "negative targetEPS", general: types.Tp_general{RateProfile: "ramp", TargetEPS: -1, RampUp: 10}, wantErr: true},
+		{name: "unknown no targetEPS", general: types.Tp_general{RateProfile: "sawtooth"}, wantErr: true},
+	}
+
+	defer func(saved types.Tp_general) { vGeneral = saved }(vGeneral)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vGeneral = tt.general
+
+			if err := checkSchedule(); (err != nil) != tt.wantErr {
+				t.Errorf("checkSchedule() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+/*****************************************************************************
+*
+*	File			: shutdown.go
+*
+* 	Created			: 19 Oct 2026
+*
+*	Description		: Graceful shutdown on SIGINT (Ctrl-C) / SIGTERM. On the first signal we stop handing out new
+*					: transactions, wait up to shutdownTimeout seconds for the in-flight transactions to complete, print
+*					: the run summary, do a final Prometheus push and exit with 128 + signal number (130 SIGINT, 143 SIGTERM).
+*					: A second signal exits immediately.
+*					: A failed transaction without continueOnError stops the run the same way, exiting with 1.
+*
+*	By				: George Leonard (georgelza@gmail.com)
+*
+*****************************************************************************/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Closed on the first SIGINT/SIGTERM or failed transaction, vStopSignal/vStopErr is set before the close.
+var vStop = make(chan struct{})
+var vStopSignal os.Signal
+var vStopErr error
+var vStopOnce sync.Once
+
+func handleSignals() {
+
+	sigs := make(chan os.Signal, 2)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		sig := <-sigs
+		grpcLog.Warningln("Shutdown requested            :", sig, "- no new transactions, waiting for in-flight transactions")
+
+		requestStop(sig, nil)
+
+		sig = <-sigs
+		grpcLog.Warningln("Second signal                 :", sig, "- exiting immediately")
+		os.Exit(signalExitCode(sig))
+	}()
+}
+
+// Stop handing out transactions, the first request wins. Called on a signal, or by a worker with the error that
+// failed it's transaction.
+func requestStop(sig os.Signal, err error) {
+
+	vStopOnce.Do(func() {
+		vStopSignal = sig
+		vStopErr = err
+		close(vStop)
+	})
+}
+
+// Has a shutdown been requested.
+func stopping() bool {
+
+	select {
+	case <-vStop:
+		return true
+
+	default:
+		return false
+
+	}
+}
+
+// Sleep for d, cut short by a shutdown, returns false if it was.
+func sleepOrStop(d time.Duration) bool {
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+
+	case <-vStop:
+		return false
+
+	}
+}
+
+// Why the run was stopped, the signal or the transaction's error.
+func stopReason() string {
+
+	if vStopErr != nil {
+		return vStopErr.Error()
+	}
+
+	return fmt.Sprintf("%v", vStopSignal)
+}
+
+// Shell convention, 128 + signal number, 1 when stopped by a failed transaction.
+func signalExitCode(sig os.Signal) int {
+
+	if s, ok := sig.(syscall.Signal); ok {
+		return 128 + int(s)
+	}
+
+	return 1
+}
@@ -0,0 +1,210 @@
+/*****************************************************************************
+*
+*	File			: sink.go
+*
+* 	Created			: 19 Oct 2026
+*
+*	Description		: Output sinks, where the events are posted to (when Call_fs_api = 1).
+*
+*					: sink
+*					:	http (default)	=> the FS API endpoint, httpposturl, see httpCALL
+*					:	kafka			=> Kafka topic per eventType, keyed by transactionId, see sink_kafka.go
+*					:	file			=> JSON lines appended to sink_file
+*					:	stdout			=> JSON lines to stdout
+*					:	memory			=> kept in memory, counts per eventType reported at the end, for testing the
+*					:					   generator without any infrastructure
+*
+*					: cassette record/replay wraps the sink, see cassette.go.
+*
+*					: Non HTTP sinks answer every event with a "204 No Content" response, so the rest of the flow (metrics,
+*					: -out.json files, RTP) is unchanged, engine responses/scores are only available via the http sink.
+*
+*	By				: George Leonard (georgelza@gmail.com)
+*
+*****************************************************************************/
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// Posts a event, Bytes is t_Payload as JSON.
+type Sink interface {
+	Send(t_Payload map[string]interface{}, Bytes []byte) (Response *http.Response, retries int, err error)
+	Close() error
+}
+
+var vSink Sink
+
+func newSink(client *http.Client) (Sink, error) {
+
+	// Replay never posts, record wraps the configured sink
+	switch vGeneral.Cassette {
+	case "":
+
+	case "replay":
+		return newCassetteSink(nil)
+
+	default:
+		base, err := newBaseSink(client)
+		if err != nil {
+			return nil, err
+		}
+		return newCassetteSink(base)
+
+	}
+
+	return newBaseSink(client)
+}
+
+func newBaseSink(client *http.Client) (Sink, error) {
+
+	switch vGeneral.Sink {
+	case "", "http":
+		return &httpSink{client: client}, nil
+
+	case "kafka":
+		return newKafkaSink()
+
+	case "file":
+		if vGeneral.Sink_file == "" {
+			return nil, fmt.Errorf("sink file requires sink_file")
+		}
+		fd, err := os.OpenFile(vGeneral.Sink_file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("sink_file open error %s: %s", vGeneral.Sink_file, err)
+		}
+		return &lineSink{w: bufio.NewWriter(fd), file: fd}, nil
+
+	case "stdout":
+		return &lineSink{w: bufio.NewWriter(os.Stdout)}, nil
+
+	case "memory":
+		return &memorySink{}, nil
+
+	}
+
+	return nil, fmt.Errorf("unknown sink %s, use http, kafka, file, stdout or memory", vGeneral.Sink)
+}
+
+// The response a non HTTP sink answers with.
+func sinkResponse(statusCode int) *http.Response {
+
+	return &http.Response{
+		Status:     fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode)),
+		StatusCode: statusCode,
+		Header:     make(http.Header),
+		Body:       http.NoBody,
+	}
+}
+
+// The FS API endpoint.
+type httpSink struct {
+	client *http.Client
+}
+
+func (s *httpSink) Send(t_Payload map[string]interface{}, Bytes []byte) (*http.Response, int, error) {
+	return httpCALL(Bytes, vGeneral.Httpposturl, s.client)
+}
+
+func (s *httpSink) Close() error {
+	return nil
+}
+
+// JSON lines, file or stdout, a line per event, flushed per event so a tail -f / crash doesn't lose any.
+type lineSink struct {
+	mu   sync.Mutex
+	w    *bufio.Writer
+	file *os.File
+}
+
+func (s *lineSink) Send(t_Payload map[string]interface{}, Bytes []byte) (*http.Response, int, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.w.Write(Bytes)
+	s.w.WriteByte('\n')
+	if err := s.w.Flush(); err != nil {
+		return nil, 0, fmt.Errorf("sink write error: %s", err)
+	}
+	atomic.AddInt64(&vStats.events, 1)
+
+	return sinkResponse(http.StatusNoContent), 0, nil
+}
+
+func (s *lineSink) Close() error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.w.Flush()
+	if s.file != nil {
+		return s.file.Close()
+	}
+
+	return nil
+}
+
+type sinkEvent struct {
+	EventType     string
+	TransactionId string
+	Bytes         []byte
+}
+
+// In memory fake, keeps every event.
+type memorySink struct {
+	mu     sync.Mutex
+	events []sinkEvent
+}
+
+func (s *memorySink) Send(t_Payload map[string]interface{}, Bytes []byte) (*http.Response, int, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.events = append(s.events, sinkEvent{
+		EventType:     fmt.Sprintf("%v", t_Payload["eventType"]),
+		TransactionId: fmt.Sprintf("%v", t_Payload["transactionId"]),
+		Bytes:         Bytes,
+	})
+	atomic.AddInt64(&vStats.events, 1)
+
+	return sinkResponse(http.StatusNoContent), 0, nil
+}
+
+// The events sent so far.
+func (s *memorySink) Events() []sinkEvent {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]sinkEvent(nil), s.events...)
+}
+
+func (s *memorySink) Close() error {
+
+	counts := make(map[string]int)
+	for _, event := range s.Events() {
+		counts[event.EventType]++
+	}
+
+	var eventTypes []string
+	for eventType := range counts {
+		eventTypes = append(eventTypes, eventType)
+	}
+	sort.Strings(eventTypes)
+
+	for _, eventType := range eventTypes {
+		grpcLog.Infof("Memory sink %-18s:  %d", eventType, counts[eventType])
+	}
+
+	return nil
+}
@@ -0,0 +1,99 @@
+/*****************************************************************************
+*
+*	File			: sink_kafka.go
+*
+* 	Created			: 19 Oct 2026
+*
+*	Description		: Kafka sink, back to where this tool started. Events are produced onto a topic per eventType, as per
+*					: kafkaTopics (eventType => topic), else kafkaTopic, else a topic named after the eventType. The message
+*					: key is the transactionId, so all events of a transaction land on the same partition, in order.
+*
+*					: A local single node broker is enough for testing, e.g.
+*					:	docker run -p 9092:9092 apache/kafka
+*					: with kafkaBrokers: ["localhost:9092"], topics are auto created if the broker allows it.
+*
+*	By				: George Leonard (georgelza@gmail.com)
+*
+*****************************************************************************/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+type kafkaSink struct {
+	writer *kafka.Writer
+}
+
+func newKafkaSink() (Sink, error) {
+
+	if len(vGeneral.KafkaBrokers) == 0 {
+		return nil, fmt.Errorf("sink kafka requires kafkaBrokers")
+	}
+
+	writer := &kafka.Writer{
+		Addr:                   kafka.TCP(vGeneral.KafkaBrokers...),
+		Balancer:               &kafka.Hash{}, // by key, aka transactionId
+		RequiredAcks:           kafka.RequireAll,
+		AllowAutoTopicCreation: true,
+		// Every worker produces a single message at a time and waits for it, don't hold it back to build a batch
+		BatchTimeout: time.Millisecond,
+		WriteTimeout: clientTimeout(),
+	}
+
+	return &kafkaSink{writer: writer}, nil
+}
+
+func kafkaTopic(eventType string) string {
+
+	if topic, ok := vGeneral.KafkaTopics[eventType]; ok {
+		return topic
+	}
+	if vGeneral.KafkaTopic != "" {
+		return vGeneral.KafkaTopic
+	}
+
+	return eventType
+}
+
+// The message for a event, keyed by transactionId.
+func kafkaMessage(t_Payload map[string]interface{}, Bytes []byte) kafka.Message {
+
+	eventType := fmt.Sprintf("%v", t_Payload["eventType"])
+
+	return kafka.Message{
+		Topic: kafkaTopic(eventType),
+		Key:   []byte(fmt.Sprintf("%v", t_Payload["transactionId"])),
+		Value: Bytes,
+		Headers: []kafka.Header{
+			{Key: "eventType", Value: []byte(eventType)},
+			{Key: "eventId", Value: []byte(fmt.Sprintf("%v", t_Payload["eventId"]))},
+		},
+	}
+}
+
+func (s *kafkaSink) Send(t_Payload map[string]interface{}, Bytes []byte) (*http.Response, int, error) {
+
+	message := kafkaMessage(t_Payload, Bytes)
+
+	if err := s.writer.WriteMessages(context.Background(), message); err != nil {
+		err = fmt.Errorf("kafka produce error, topic %s: %s", message.Topic, err)
+		grpcLog.Errorln(err)
+		return nil, 0, err
+
+	}
+	atomic.AddInt64(&vStats.events, 1)
+
+	return sinkResponse(http.StatusNoContent), 0, nil
+}
+
+func (s *kafkaSink) Close() error {
+	return s.writer.Close()
+}
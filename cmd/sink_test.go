@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"cmd/types"
+
+	"github.com/segmentio/kafka-go"
+)
+
+func TestMemorySink(t *testing.T) {
+
+	payload := func(eventType string, transactionId string) map[string]interface{} {
+		return map[string]interface{}{"eventType": eventType, "transactionId": transactionId, "eventId": eventType + "-" + transactionId}
+	}
+
+	tests := []struct {
+		name     string
+		payloads []map[string]interface{}
+		want     []sinkEvent
+	}{
+		{
+			name: "none",
+		},
+		{
+			name:     "payment pair in order",
+			payloads: []map[string]interface{}{payload("paymentRT", "t1"), payload("paymentNRT", "t1")},
+			want:     []sinkEvent{{EventType: "paymentRT", TransactionId: "t1"}, {EventType: "paymentNRT", TransactionId: "t1"}},
+		},
+		{
+			name:     "request to pay then payments",
+			payloads: []map[string]interface{}{payload("requestToPay", "t2"), payload("requestToPayResponse", "t2"), payload("paymentRT", "t2"), payload("paymentNRT", "t2")},
+			want: []sinkEvent{{EventType: "requestToPay", TransactionId: "t2"}, {EventType: "requestToPayResponse", TransactionId: "t2"},
+				{EventType: "paymentRT", TransactionId: "t2"}, {EventType: "paymentNRT", TransactionId: "t2"}},
+		},
+		{
+			name:     "missing fields",
+			payloads: []map[string]interface{}{{}},
+			want:     []sinkEvent{{EventType: "<nil>", TransactionId: "<nil>"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := &memorySink{}
+			before := atomic.LoadInt64(&vStats.events)
+
+			for i, t_Payload := range tt.payloads {
+				Bytes, _ := json.Marshal(t_Payload)
+				tt.want[i].Bytes = Bytes
+
+				Response, retries, err := sink.Send(t_Payload, Bytes)
+				if err != nil || retries != 0 {
+					t.Fatalf("Send() = %v retries %d, want no error and no retries", err, retries)
+				}
+				if Response.StatusCode != http.StatusNoContent || Response.Status != "204 No Content" {
+					t.Errorf("Send() response = %s, want 204 No Content", Response.Status)
+				}
+			}
+
+			if got := sink.Events(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Events() = %+v, want %+v", got, tt.want)
+			}
+			if got := atomic.LoadInt64(&vStats.events) - before; got != int64(len(tt.payloads)) {
+				t.Errorf("events counted = %d, want %d", got, len(tt.payloads))
+			}
+			if err := sink.Close(); err != nil {
+				t.Errorf("Close() error = %v", err)
+			}
+		})
+	}
+}
+
+// Workers share the sink.
+func TestMemorySinkConcurrent(t *testing.T) {
+
+	sink := &memorySink{}
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				sink.Send(map[string]interface{}{"eventType": "paymentNRT"}, nil)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := len(sink.Events()); got != 800 {
+		t.Errorf("len(Events()) = %d, want 800", got)
+	}
+}
+
+func TestKafkaTopic(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		topic     string
+		topics    map[string]string
+		eventType string
+		want      string
+	}{
+		{name: "per eventType", eventType: "paymentRT", want: "paymentRT"},
+		{name: "single topic", topic: "fs-events", eventType: "paymentRT", want: "fs-events"},
+		{name: "mapped", topic: "fs-events", topics: map[string]string{"paymentRT": "fs-payments-rt"}, eventType: "paymentRT", want: "fs-payments-rt"},
+		{name: "not mapped, single topic", topic: "fs-events", topics: map[string]string{"paymentRT": "fs-payments-rt"}, eventType: "addPayeeNRT", want: "fs-events"},
+		{name: "not mapped, per eventType", topics: map[string]string{"paymentRT": "fs-payments-rt"}, eventType: "requestToPay", want: "requestToPay"},
+	}
+
+	defer func(saved types.Tp_general) { vGeneral = saved }(vGeneral)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vGeneral = types.Tp_general{KafkaTopic: tt.topic, KafkaTopics: tt.topics}
+
+			if got := kafkaTopic(tt.eventType); got != tt.want {
+				t.Errorf("kafkaTopic(%s) = %s, want %s", tt.eventType, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKafkaMessage(t *testing.T) {
+
+	tests := []struct {
+		name        string
+		payload     map[string]interface{}
+		wantTopic   string
+		wantKey     string
+		wantHeaders []kafka.Header
+	}{
+		{
+			name:        "payment",
+			payload:     map[string]interface{}{"eventType": "paymentNRT", "transactionId": "t1", "eventId": "e1"},
+			wantTopic:   "fs-payments",
+			wantKey:     "t1",
+			wantHeaders: []kafka.Header{{Key: "eventType", Value: []byte("paymentNRT")}, {Key: "eventId", Value: []byte("e1")}},
+		},
+		{
+			name:        "request to pay, same transaction same key",
+			payload:     map[string]interface{}{"eventType": "requestToPay", "transactionId": "t1", "eventId": "e2"},
+			wantTopic:   "requestToPay",
+			wantKey:     "t1",
+			wantHeaders: []kafka.Header{{Key: "eventType", Value: []byte("requestToPay")}, {Key: "eventId", Value: []byte("e2")}},
+		},
+	}
+
+	defer func(saved types.Tp_general) { vGeneral = saved }(vGeneral)
+	vGeneral = types.Tp_general{KafkaTopics: map[string]string{"paymentNRT": "fs-payments"}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			Bytes, _ := json.Marshal(tt.payload)
+
+			message := kafkaMessage(tt.payload, Bytes)
+			if message.Topic != tt.wantTopic || string(message.Key) != tt.wantKey || !bytes.Equal(message.Value, Bytes) {
+				t.Errorf("kafkaMessage() topic %s key %s value %s, want %s %s %s", message.Topic, message.Key, message.Value, tt.wantTopic, tt.wantKey, Bytes)
+			}
+			if !reflect.DeepEqual(message.Headers, tt.wantHeaders) {
+				t.Errorf("kafkaMessage() headers = %v, want %v", message.Headers, tt.wantHeaders)
+			}
+		})
+	}
+}
+
+// sink file, a JSON line per event, appended across runs.
+func TestFileSink(t *testing.T) {
+
+	defer func(saved types.Tp_general) { vGeneral = saved }(vGeneral)
+	vGeneral = types.Tp_general{Sink: "file", Sink_file: filepath.Join(t.TempDir(), "events.jsonl")}
+
+	payloads := []map[string]interface{}{
+		{"eventType": "paymentRT", "transactionId": "t1"},
+		{"eventType": "paymentNRT", "transactionId": "t1"},
+	}
+
+	var want []map[string]interface{}
+	for run := 0; run < 2; run++ {
+		sink, err := newBaseSink(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, t_Payload := range payloads {
+			Bytes, _ := json.Marshal(t_Payload)
+			Response, _, err := sink.Send(t_Payload, Bytes)
+			if err != nil || Response.StatusCode != http.StatusNoContent {
+				t.Fatalf("Send() = %v, %v, want 204", Response, err)
+			}
+			want = append(want, t_Payload)
+		}
+		if err = sink.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+	}
+
+	fd, err := os.Open(vGeneral.Sink_file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+
+	var got []map[string]interface{}
+	scanner := bufio.NewScanner(fd)
+	for scanner.Scan() {
+		var t_Payload map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &t_Payload); err != nil {
+			t.Fatalf("line %q: %s", scanner.Text(), err)
+		}
+		got = append(got, t_Payload)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("sink_file = %v, want %v", got, want)
+	}
+}
+
+func TestFileSinkRequiresFile(t *testing.T) {
+
+	defer func(saved types.Tp_general) { vGeneral = saved }(vGeneral)
+	vGeneral = types.Tp_general{Sink: "file"}
+
+	if _, err := newBaseSink(nil); err == nil {
+		t.Error("newBaseSink() succeeded without sink_file")
+	}
+}
@@ -0,0 +1,132 @@
+/*****************************************************************************
+*
+*	File			: soak.go
+*
+* 	Created			: 19 Oct 2026
+*
+*	Description		: Soak mode, run for a wall-clock duration (soakDuration, e.g. "12h") or, if not set, until stopped
+*					: via Ctrl-C/SIGTERM, instead of for testsize transactions. When reading from input_path the scenario
+*					: files are cycled through (each pass gets fresh transactionId/eventId's).
+*
+*					: Every progressInterval seconds a checkpoint is logged, totals, the rates over the last interval and
+*					: the Go runtime stats (goroutines, heap, GC), which should stay flat over a long run, a steady climb
+*					: points at a leak in the tool, a steady drop in rate/climb in latency at engine degradation.
+*
+*					: pprofAddress (e.g. "localhost:6060"), exposes /debug/pprof/* and /debug/vars (expvar, incl. the
+*					: run counters under "fs_producer") for the duration of the run.
+*
+*					: NOTE json_to_file/engineResponse_to_file still write 2+ files per transaction, disk usage on a 12h
+*					: run can be substantial.
+*					: The outcomes (results_file, golden, report) are kept per scenario file, on fake data the first
+*					: maxRecordSteps records only.
+*
+*	By				: George Leonard (georgelza@gmail.com)
+*
+*****************************************************************************/
+
+package main
+
+import (
+	"expvar"
+	"math"
+	"net/http"
+	_ "net/http/pprof"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// Closed when the soak duration has passed, never closed when not soaking or soaking until stopped.
+var vSoakEnd = make(chan struct{})
+
+// Work out the number of transactions to hand out, in soak mode that's "unlimited" and the run is ended by
+// soakDuration or a shutdown signal.
+func soakSetup(todo_count int) int {
+
+	if vGeneral.Soak != 1 {
+		return todo_count
+	}
+
+	if vGeneral.SoakDuration != "" {
+		duration, err := time.ParseDuration(vGeneral.SoakDuration)
+		if err != nil {
+			grpcLog.Fatalln("Invalid soakDuration: ", vGeneral.SoakDuration, err)
+
+		}
+		time.AfterFunc(duration, func() { close(vSoakEnd) })
+		grpcLog.Infoln("Soak run for                  :", duration)
+
+	} else {
+		grpcLog.Infoln("Soak run until stopped        : Ctrl-C/SIGTERM")
+
+	}
+
+	return math.MaxInt32
+}
+
+// Log a progress checkpoint every progressInterval seconds, until done is closed.
+func runProgress(vStart time.Time, done <-chan struct{}) {
+
+	interval := time.Duration(vGeneral.ProgressInterval) * time.Second
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var lastTxns, lastEvents int64
+	var mem runtime.MemStats
+
+	for {
+		select {
+		case <-done:
+			return
+
+		case <-ticker.C:
+		}
+
+		txns := atomic.LoadInt64(&vStats.txns)
+		events := atomic.LoadInt64(&vStats.events)
+		runtime.ReadMemStats(&mem)
+
+		grpcLog.Infoln("")
+		grpcLog.Infoln("Checkpoint, Elapsed (Seconds) : ", time.Since(vStart).Seconds())
+		grpcLog.Infoln("Records Processed             : ", txns)
+		grpcLog.Infoln("Events Posted                 : ", events)
+		grpcLog.Infoln("Events Failed                 : ", atomic.LoadInt64(&vStats.failed))
+		grpcLog.Infoln("Retries                       : ", atomic.LoadInt64(&vStats.retries))
+		grpcLog.Infof("Last interval                 :  %.3f Txns/Second, %.3f Events/Second", float64(txns-lastTxns)/interval.Seconds(), float64(events-lastEvents)/interval.Seconds())
+		grpcLog.Infof("Runtime                       :  %d Goroutines, %.1f MB Heap, %.1f MB Sys, %d GC", runtime.NumGoroutine(), float64(mem.HeapAlloc)/1048576, float64(mem.Sys)/1048576, mem.NumGC)
+		grpcLog.Infoln("")
+
+		lastTxns = txns
+		lastEvents = events
+	}
+}
+
+// pprof and expvar, on their own listener, both register on the default mux.
+func startPprof() {
+
+	if vGeneral.PprofAddress == "" {
+		return
+	}
+
+	expvar.Publish("fs_producer", expvar.Func(func() interface{} {
+		return map[string]int64{
+			"txns":    atomic.LoadInt64(&vStats.txns),
+			"events":  atomic.LoadInt64(&vStats.events),
+			"failed":  atomic.LoadInt64(&vStats.failed),
+			"retries": atomic.LoadInt64(&vStats.retries),
+			"behind":  atomic.LoadInt64(&vStats.behind),
+		}
+	}))
+
+	go func() {
+		grpcLog.Infoln("pprof/expvar listening on     :", vGeneral.PprofAddress)
+		if err := http.ListenAndServe(vGeneral.PprofAddress, nil); err != nil {
+			grpcLog.Errorln("pprof listener error: ", err)
+
+		}
+	}()
+}
@@ -0,0 +1,290 @@
+/*****************************************************************************
+*
+*	File			: tlsconfig.go
+*
+* 	Created			: 19 Oct 2026
+*
+*	Description		: mTLS configuration for the API client.
+*
+*					: Client identity	: pkcs12_file (+ pkcs12_password) if set, else cert_file/cert_key (PEM), all in cert_dir
+*					: Server trust		: ca_file (PEM bundle, in cert_dir), "" => the system roots.
+*					:					: Verification is on, insecureSkipVerify = 1 is the explicit opt-out (self signed
+*					:					: test endpoints without a CA bundle).
+*					: serverName		: SNI/verification name override, for when we connect via IP or a alias
+*					: tlsMinVersion		: "1.2" (default) or "1.3"
+*					: tlsCipherSuites	: TLS 1.2 cipher suite names as per crypto/tls (e.g. TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256),
+*					:					: empty => Go defaults, TLS 1.3 suites are not configurable.
+*
+*					: At startup the client and CA certificates are checked for expiry, within certExpiryWarnDays (default
+*					: 30) we warn, expired or within certExpiryFailDays we refuse to start. Of the ca_file bundle only the
+*					: CA certificates the client chain leads up to count, the others are only warned about.
+*
+*	By				: George Leonard (georgelza@gmail.com)
+*
+*****************************************************************************/
+
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+const defaultCertExpiryWarnDays = 30
+
+func buildTLSConfig() (*tls.Config, error) {
+
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: vGeneral.InsecureSkipVerify == 1,
+		ServerName:         vGeneral.ServerName,
+	}
+
+	// Client identity
+	cert, err := loadClientCertificate()
+	if err != nil {
+		grpcLog.Errorln(err)
+		return nil, err
+
+	}
+	tlsConfig.Certificates = []tls.Certificate{cert}
+
+	// Server trust, nil RootCAs => system roots
+	var caCerts []*x509.Certificate
+	if vGeneral.Ca_file != "" {
+		caPEM, err := os.ReadFile(vGeneral.Ca_file)
+		if err != nil {
+			x := fmt.Sprintf("Problem reading: %s Error: %s", vGeneral.Ca_file, err)
+			err = errors.New(x)
+			grpcLog.Errorln(err)
+			return nil, err
+
+		}
+
+		caCertPool := x509.NewCertPool()
+		if !caCertPool.AppendCertsFromPEM(caPEM) {
+			err = fmt.Errorf("no certificates found in %s", vGeneral.Ca_file)
+			grpcLog.Errorln(err)
+			return nil, err
+
+		}
+		tlsConfig.RootCAs = caCertPool
+		caCerts = parsePEMCertificates(caPEM)
+
+	}
+
+	if tlsConfig.InsecureSkipVerify {
+		grpcLog.Warningln("* TLS server certificate verification is DISABLED (insecureSkipVerify)")
+
+	}
+
+	switch vGeneral.TLSMinVersion {
+	case "", "1.2":
+		tlsConfig.MinVersion = tls.VersionTLS12
+
+	case "1.3":
+		tlsConfig.MinVersion = tls.VersionTLS13
+
+	default:
+		err = fmt.Errorf("unsupported tlsMinVersion %s, use 1.2 or 1.3", vGeneral.TLSMinVersion)
+		grpcLog.Errorln(err)
+		return nil, err
+
+	}
+
+	if len(vGeneral.TLSCipherSuites) > 0 {
+		suites := make(map[string]uint16)
+		for _, suite := range tls.CipherSuites() {
+			suites[suite.Name] = suite.ID
+		}
+
+		for _, name := range vGeneral.TLSCipherSuites {
+			id, ok := suites[name]
+			if !ok {
+				err = fmt.Errorf("unknown or insecure tlsCipherSuites entry %s", name)
+				grpcLog.Errorln(err)
+				return nil, err
+
+			}
+			tlsConfig.CipherSuites = append(tlsConfig.CipherSuites, id)
+		}
+	}
+
+	// Expiry, client chain and CA bundle
+	if err = checkCertExpiry(cert.Certificate, caCerts); err != nil {
+		grpcLog.Errorln(err)
+		return nil, err
+
+	}
+
+	return tlsConfig, nil
+}
+
+// From the PKCS#12 file if configured, else the PEM cert/key pair.
+func loadClientCertificate() (tls.Certificate, error) {
+
+	if vGeneral.Pkcs12_file == "" {
+		cert, err := tls.LoadX509KeyPair(vGeneral.Cert_file, vGeneral.Cert_key)
+		if err != nil {
+			return cert, fmt.Errorf("Problem with LoadX509KeyPair: %s Error: %s", vGeneral.Cert_key, err)
+		}
+
+		return cert, nil
+	}
+
+	pfx, err := os.ReadFile(vGeneral.Pkcs12_file)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("Problem reading: %s Error: %s", vGeneral.Pkcs12_file, err)
+	}
+
+	// DecodeChain keeps the CA chain that is often bundled in the file, and handles the PBES2/AES encryption
+	// OpenSSL 3 uses by default as well as the legacy RC2/3DES files
+	key, leaf, caCerts, err := pkcs12.DecodeChain(pfx, vGeneral.Pkcs12_password)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("Problem decoding PKCS#12: %s Error: %s", vGeneral.Pkcs12_file, err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("Problem with PKCS#12 private key: %s Error: %s", vGeneral.Pkcs12_file, err)
+	}
+
+	// Via PEM, so X509KeyPair checks the key belongs to the certificate
+	var certPEM, keyPEM bytes.Buffer
+	pem.Encode(&certPEM, &pem.Block{Type: "CERTIFICATE", Bytes: leaf.Raw})
+	for _, ca := range caCerts {
+		pem.Encode(&certPEM, &pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw})
+	}
+	pem.Encode(&keyPEM, &pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+	cert, err := tls.X509KeyPair(certPEM.Bytes(), keyPEM.Bytes())
+	if err != nil {
+		return cert, fmt.Errorf("Problem with PKCS#12 key pair: %s Error: %s", vGeneral.Pkcs12_file, err)
+	}
+
+	return cert, nil
+}
+
+func parsePEMCertificates(data []byte) (certs []*x509.Certificate) {
+
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return certs
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		if cert, err := x509.ParseCertificate(block.Bytes); err == nil {
+			certs = append(certs, cert)
+		}
+	}
+}
+
+// Warn when a certificate expires within certExpiryWarnDays, fail when expired, not yet valid or within certExpiryFailDays.
+// Only the client chain and the CA certificates anchoring it fail, the rest of the ca_file bundle (corporate bundles
+// often still carry expired legacy roots) is warned about.
+func checkCertExpiry(clientChain [][]byte, caCerts []*x509.Certificate) error {
+
+	warnDays := vGeneral.CertExpiryWarnDays
+	if warnDays <= 0 {
+		warnDays = defaultCertExpiryWarnDays
+	}
+
+	type checkedCert struct {
+		label string
+		cert  *x509.Certificate
+		fail  bool
+	}
+	var certs []checkedCert
+
+	var chain []*x509.Certificate
+	for i, der := range clientChain {
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return fmt.Errorf("Problem parsing client certificate: %s", err)
+		}
+		label := "client certificate"
+		if i > 0 {
+			label = "client chain certificate"
+		}
+		certs = append(certs, checkedCert{fmt.Sprintf("%s %s", label, cert.Subject), cert, true})
+		chain = append(chain, cert)
+	}
+
+	anchors := anchoringCAs(chain, caCerts)
+	for _, cert := range caCerts {
+		certs = append(certs, checkedCert{fmt.Sprintf("CA certificate %s", cert.Subject), cert, anchors[cert]})
+	}
+
+	now := time.Now()
+	for _, c := range certs {
+		days := int(c.cert.NotAfter.Sub(now).Hours() / 24)
+
+		var problem string
+		switch {
+		case now.Before(c.cert.NotBefore):
+			problem = fmt.Sprintf("%s is not valid before %s", c.label, c.cert.NotBefore)
+
+		case now.After(c.cert.NotAfter):
+			problem = fmt.Sprintf("%s expired on %s", c.label, c.cert.NotAfter)
+
+		case days < vGeneral.CertExpiryFailDays:
+			problem = fmt.Sprintf("%s expires in %d days (%s), certExpiryFailDays is %d", c.label, days, c.cert.NotAfter, vGeneral.CertExpiryFailDays)
+
+		case days < warnDays:
+			grpcLog.Warningf("* %s expires in %d days (%s)", c.label, days, c.cert.NotAfter)
+
+		default:
+			if vGeneral.Debuglevel > 1 {
+				grpcLog.Infof("* %s valid until %s", c.label, c.cert.NotAfter)
+
+			}
+		}
+
+		if problem == "" {
+			continue
+
+		} else if c.fail {
+			return errors.New(problem)
+
+		}
+		grpcLog.Warningf("* %s, not in the client chain, ignored", problem)
+	}
+
+	return nil
+}
+
+// The ca_file certificates the client chain leads up to, issuer by issuer from the top of the chain.
+func anchoringCAs(chain []*x509.Certificate, caCerts []*x509.Certificate) map[*x509.Certificate]bool {
+
+	anchors := make(map[*x509.Certificate]bool)
+	if len(chain) == 0 {
+		return anchors
+	}
+
+	cert := chain[len(chain)-1]
+	for {
+		var issuer *x509.Certificate
+		for _, ca := range caCerts {
+			if !anchors[ca] && bytes.Equal(cert.RawIssuer, ca.RawSubject) && cert.CheckSignatureFrom(ca) == nil {
+				issuer = ca
+				break
+			}
+		}
+		if issuer == nil {
+			return anchors
+		}
+
+		anchors[issuer] = true
+		cert = issuer
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"cmd/types"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// A certificate valid from notBefore to notAfter (relative to now), signed by parent, self signed when nil.
+func newTestCert(t *testing.T, name string, isCA bool, notBefore time.Duration, notAfter time.Duration, parent *testCert) *testCert {
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(notBefore),
+		NotAfter:              time.Now().Add(notAfter),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &testCert{cert: cert, key: key}
+}
+
+func TestCheckCertExpiry(t *testing.T) {
+
+	year := 365 * 24 * time.Hour
+
+	root := newTestCert(t, "root", true, -year, 10*year, nil)
+	intermediate := newTestCert(t, "intermediate", true, -year, 5*year, root)
+	client := newTestCert(t, "client", false, -time.Hour, year, intermediate)
+
+	expiredRoot := newTestCert(t, "root", true, -10*year, -year, nil)
+	expiredIntermediate := newTestCert(t, "intermediate", true, -year, -time.Hour, root)
+	clientOfExpired := newTestCert(t, "client", false, -time.Hour, year, expiredIntermediate)
+
+	legacyRoot := newTestCert(t, "legacy root", true, -20*year, -year, nil)
+	futureRoot := newTestCert(t, "future root", true, year, 2*year, nil)
+	expiredClient := newTestCert(t, "client", false, -year, -time.Hour, intermediate)
+
+	certs := func(tcs ...*testCert) (certs []*x509.Certificate) {
+		for _, tc := range tcs {
+			certs = append(certs, tc.cert)
+		}
+		return certs
+	}
+	chain := func(tcs ...*testCert) (ders [][]byte) {
+		for _, tc := range tcs {
+			ders = append(ders, tc.cert.Raw)
+		}
+		return ders
+	}
+
+	tests := []struct {
+		name     string
+		chain    [][]byte
+		caCerts  []*x509.Certificate
+		failDays int
+		wantErr  bool
+	}{
+		{name: "valid chain and bundle", chain: chain(client), caCerts: certs(root, intermediate)},
+		{name: "expired legacy roots in the bundle", chain: chain(client), caCerts: certs(legacyRoot, root, futureRoot, intermediate)},
+		{name: "expired client", chain: chain(expiredClient), caCerts: certs(root, intermediate), wantErr: true},
+		{name: "expired chain certificate", chain: chain(clientOfExpired, expiredIntermediate), caCerts: certs(root), wantErr: true},
+		{name: "expired anchoring intermediate", chain: chain(clientOfExpired), caCerts: certs(root, expiredIntermediate), wantErr: true},
+		{name: "same name root not anchoring", chain: chain(client, intermediate), caCerts: certs(expiredRoot, root)},
+		{name: "anchoring root within certExpiryFailDays", chain: chain(client), caCerts: certs(root, intermediate), failDays: 4000, wantErr: true},
+		{name: "no bundle", chain: chain(client)},
+	}
+
+	defer func(saved types.Tp_general) { vGeneral = saved }(vGeneral)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vGeneral = types.Tp_general{CertExpiryFailDays: tt.failDays}
+
+			if err := checkCertExpiry(tt.chain, tt.caCerts); (err != nil) != tt.wantErr {
+				t.Errorf("checkCertExpiry() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
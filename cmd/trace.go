@@ -0,0 +1,229 @@
+/*****************************************************************************
+*
+*	File			: trace.go
+*
+* 	Created			: 19 Oct 2026
+*
+*	Description		: Per request phase timings via net/http/httptrace, to tell whether slow calls come from DNS, the
+*					: TCP connect (to the proxy if one is configured), the mTLS handshake or server processing.
+*
+*					:	dns			: DNS lookup
+*					:	connect		: TCP connect
+*					:	tls			: TLS handshake
+*					:	server		: request written -> first response byte, the engine (and proxy) processing time
+*					:	ttfb		: start of the request -> first response byte
+*					:	bodyRead	: reading the response body, from the first Read to EOF
+*					:	total		: ttfb + bodyRead, time spent waiting on the API (each body is read and closed
+*					:				: right after its event was posted, before the transaction's next event)
+*
+*					: dns/connect/tls are 0 when a pooled connection was reused. The timings of the final attempt are
+*					: logged (debuglevel > 2), added to the -out.json files and observed into fs_api_phase_seconds,
+*					: new vs reused connections are counted in fs_api_connections_total.
+*
+*	By				: George Leonard (georgelza@gmail.com)
+*
+*****************************************************************************/
+
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type callTimingKey struct{}
+
+type callTiming struct {
+	mu sync.Mutex
+
+	start, dnsStart, dnsDone, connectStart, connectDone, tlsStart, tlsDone time.Time
+	wroteRequest, firstByte, bodyStart, bodyDone                           time.Time
+
+	reused  bool
+	wasIdle bool
+
+	once sync.Once
+}
+
+// Add the trace hooks to the request, the timings are retrieved from the response using callTimingOf.
+func traceRequest(Request *http.Request) *http.Request {
+
+	t := &callTiming{start: time.Now()}
+
+	set := func(field *time.Time, first bool) {
+		t.mu.Lock()
+		if !first || field.IsZero() {
+			*field = time.Now()
+		}
+		t.mu.Unlock()
+	}
+
+	trace := &httptrace.ClientTrace{
+		DNSStart:          func(httptrace.DNSStartInfo) { set(&t.dnsStart, true) },
+		DNSDone:           func(httptrace.DNSDoneInfo) { set(&t.dnsDone, false) },
+		ConnectStart:      func(string, string) { set(&t.connectStart, true) },
+		ConnectDone:       func(string, string, error) { set(&t.connectDone, false) },
+		TLSHandshakeStart: func() { set(&t.tlsStart, true) },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { set(&t.tlsDone, false) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			t.reused = info.Reused
+			t.wasIdle = info.WasIdle
+			t.mu.Unlock()
+		},
+		WroteRequest:         func(httptrace.WroteRequestInfo) { set(&t.wroteRequest, false) },
+		GotFirstResponseByte: func() { set(&t.firstByte, true) },
+	}
+
+	ctx := context.WithValue(Request.Context(), callTimingKey{}, t)
+
+	return Request.WithContext(httptrace.WithClientTrace(ctx, trace))
+}
+
+// Time the body read, the timing completes when the body reaches EOF or is closed.
+func traceResponse(Response *http.Response) {
+
+	if t := callTimingOf(Response); t != nil {
+		Response.Body = &timedBody{ReadCloser: Response.Body, timing: t}
+	}
+}
+
+func callTimingOf(Response *http.Response) *callTiming {
+
+	if Response == nil || Response.Request == nil {
+		return nil
+	}
+	t, _ := Response.Request.Context().Value(callTimingKey{}).(*callTiming)
+
+	return t
+}
+
+type timedBody struct {
+	io.ReadCloser
+	timing *callTiming
+}
+
+func (b *timedBody) Read(p []byte) (int, error) {
+
+	b.timing.mu.Lock()
+	if b.timing.bodyStart.IsZero() {
+		b.timing.bodyStart = time.Now()
+	}
+	b.timing.mu.Unlock()
+
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.timing.complete()
+	}
+
+	return n, err
+}
+
+func (b *timedBody) Close() error {
+
+	b.timing.complete()
+
+	return b.ReadCloser.Close()
+}
+
+func since(from, to time.Time) time.Duration {
+
+	if from.IsZero() || to.IsZero() {
+		return 0
+	}
+
+	return to.Sub(from)
+}
+
+// The phases, as durations.
+func (t *callTiming) phases() map[string]time.Duration {
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	ttfb := since(t.start, t.firstByte)
+	bodyRead := since(t.bodyStart, t.bodyDone)
+
+	return map[string]time.Duration{
+		"dns":      since(t.dnsStart, t.dnsDone),
+		"connect":  since(t.connectStart, t.connectDone),
+		"tls":      since(t.tlsStart, t.tlsDone),
+		"server":   since(t.wroteRequest, t.firstByte),
+		"ttfb":     ttfb,
+		"bodyRead": bodyRead,
+		"total":    ttfb + bodyRead,
+	}
+}
+
+// The response body was read, record the timings once.
+func (t *callTiming) complete() {
+
+	t.once.Do(func() {
+		t.mu.Lock()
+		t.bodyDone = time.Now()
+		reused := t.reused
+		t.mu.Unlock()
+
+		if reused {
+			atomic.AddInt64(&vStats.connReused, 1)
+		} else {
+			atomic.AddInt64(&vStats.connNew, 1)
+		}
+
+		if vGeneral.Prometheus_enabled == 1 {
+			for phase, d := range t.phases() {
+				m.api_phase_duration.With(prometheus.Labels{"hostname": vGeneral.Hostname, "phase": phase}).Observe(d.Seconds())
+			}
+			m.api_connections.With(prometheus.Labels{"hostname": vGeneral.Hostname, "reused": map[bool]string{true: "true", false: "false"}[reused]}).Inc()
+
+		}
+	})
+}
+
+// For the -out.json files, milliseconds.
+func (t *callTiming) report() map[string]interface{} {
+
+	if t == nil {
+		return nil
+	}
+
+	r := make(map[string]interface{})
+	for phase, d := range t.phases() {
+		r[phase] = float64(d.Microseconds()) / 1000
+	}
+
+	t.mu.Lock()
+	r["reused"] = t.reused
+	r["wasIdle"] = t.wasIdle
+	t.mu.Unlock()
+
+	return r
+}
+
+// debuglevel > 2
+func logCallTiming(label string, t *callTiming) {
+
+	if t == nil || vGeneral.Debuglevel <= 2 {
+		return
+	}
+
+	p := t.phases()
+	t.mu.Lock()
+	reused := t.reused
+	t.mu.Unlock()
+
+	grpcLog.Infof("%-30s: dns %.3f, connect %.3f, tls %.3f, server %.3f, ttfb %.3f, body %.3f, total %.3f ms, reused %v",
+		label, ms(p["dns"]), ms(p["connect"]), ms(p["tls"]), ms(p["server"]), ms(p["ttfb"]), ms(p["bodyRead"]), ms(p["total"]), reused)
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
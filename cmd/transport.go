@@ -0,0 +1,112 @@
+/*****************************************************************************
+*
+*	File			: transport.go
+*
+* 	Created			: 19 Oct 2026
+*
+*	Description		: HTTP client transport settings, timeouts, connection pooling, HTTP/2 and gzip request compression.
+*
+*					: httpTimeout				ms, whole request incl. reading the response body, each body is read right
+*					:							after its event was posted.
+*					: dialTimeout				ms, TCP connect
+*					: tlsHandshakeTimeout		ms, mTLS handshake
+*					: responseHeaderTimeout		ms, request written -> response headers, 0 => covered by httpTimeout
+*					: maxIdleConnsPerHost		idle (pooled) connections kept per host, 0 => two per worker
+*					: idleConnTimeout			seconds, a pooled connection is closed after being idle this long
+*					: keepAlivePeriod			seconds, TCP keep-alive probes
+*					: disableKeepAlives			0/1, 1 => a new connection per request, no pooling
+*					: http2						0/1, 1 => negotiate HTTP/2 (TLS, ALPN), 0 => HTTP/1.1 only
+*					: gzipRequests				0/1, 1 => request body gzip compressed, Content-Encoding: gzip
+*
+*					: A timed out request is retried as per maxRetries (reason "timeout").
+*
+*	By				: George Leonard (georgelza@gmail.com)
+*
+*****************************************************************************/
+
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Defaults, used when the setting is 0
+const (
+	defaultHttpTimeout         = 30000 // ms
+	defaultDialTimeout         = 10000 // ms
+	defaultTLSHandshakeTimeout = 10000 // ms
+	defaultIdleConnTimeout     = 90    // seconds
+	defaultKeepAlivePeriod     = 30    // seconds
+)
+
+func orDefault(value int, def int) int {
+
+	if value <= 0 {
+		return def
+	}
+
+	return value
+}
+
+func newTransport(tlsConfig *tls.Config) *http.Transport {
+
+	maxIdle := vGeneral.MaxIdleConnsPerHost
+	if maxIdle <= 0 {
+		// Keep two idle connections per worker, else connections are closed and re-opened between transactions when
+		// several workers return theirs at the same time
+		maxIdle = 2 * orDefault(vGeneral.Workers, 1)
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   time.Duration(orDefault(vGeneral.DialTimeout, defaultDialTimeout)) * time.Millisecond,
+		KeepAlive: time.Duration(orDefault(vGeneral.KeepAlivePeriod, defaultKeepAlivePeriod)) * time.Second,
+	}
+
+	transport := &http.Transport{
+		TLSClientConfig:       tlsConfig,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   time.Duration(orDefault(vGeneral.TLSHandshakeTimeout, defaultTLSHandshakeTimeout)) * time.Millisecond,
+		ResponseHeaderTimeout: time.Duration(vGeneral.ResponseHeaderTimeout) * time.Millisecond,
+		MaxIdleConns:          maxIdle * 2,
+		MaxIdleConnsPerHost:   maxIdle,
+		IdleConnTimeout:       time.Duration(orDefault(vGeneral.IdleConnTimeout, defaultIdleConnTimeout)) * time.Second,
+		DisableKeepAlives:     vGeneral.DisableKeepAlives == 1,
+	}
+
+	if vGeneral.Http2 == 1 {
+		// With our own TLSClientConfig/DialContext HTTP/2 is only attempted if forced
+		transport.ForceAttemptHTTP2 = true
+
+	} else {
+		// A non nil, empty TLSNextProto disables HTTP/2
+		transport.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+
+	}
+
+	return transport
+}
+
+func clientTimeout() time.Duration {
+	return time.Duration(orDefault(vGeneral.HttpTimeout, defaultHttpTimeout)) * time.Millisecond
+}
+
+// gzip the request body, done once per event, retries re-post the same compressed body.
+func gzipBody(Bytes []byte) ([]byte, error) {
+
+	var buf bytes.Buffer
+
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(Bytes); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
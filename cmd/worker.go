@@ -0,0 +1,119 @@
+/*****************************************************************************
+*
+*	File			: worker.go
+*
+* 	Created			: 19 Oct 2026
+*
+*	Description		: Worker pool, posts <workers> transactions in parallel. A transaction is always handled by a single
+*					: worker, so the ordering rules within a transaction still hold:
+*					:	paymentRT before paymentNRT
+*					:	addPayeeRT before addPayeeNRT
+*					:	requestToPay before requestToPayResponse before the payment pair
+*
+*	By				: George Leonard (georgelza@gmail.com)
+*
+*****************************************************************************/
+
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// Run level counters, updated by the workers, use sync/atomic to access.
+type runStats struct {
+	txns       int64 // transactions processed
+	events     int64 // events posted onto the API endpoint
+	retries    int64 // API calls retried
+	failed     int64 // events dead-lettered, failed or not posted due to a earlier failure in the transaction
+	behind     int64 // open-loop, transactions started later than lagThreshold after their scheduled time
+	maxLag     int64 // open-loop, worst schedule lag, nanoseconds
+	connNew    int64 // API calls on a new connection
+	connReused int64 // API calls on a reused (pooled) connection
+}
+
+var vStats runStats
+
+// A unit of work for a worker, the record number and the time the transaction was scheduled to start. Closed loop
+// that's the time a worker picked it up, open-loop it's the time as per the rate profile.
+type txnJob struct {
+	count     int
+	scheduled time.Time
+}
+
+// Feed the record numbers 0 -> todo_count-1 to the workers, either as fast as the workers take them (closed loop) or as
+// per the rateProfile (open-loop), and wait for all of them to complete. On a shutdown signal we stop feeding and wait
+// at most shutdownTimeout seconds for the in-flight transactions. In soak mode feeding also stops once soakDuration passed.
+// Returns false if transactions were still in flight after shutdownTimeout.
+func runWorkers(todo_count int, process func(job txnJob)) (drained bool) {
+
+	workers := vGeneral.Workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	records := make(chan txnJob)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range records {
+				if job.scheduled.IsZero() {
+					job.scheduled = time.Now()
+				}
+				process(job)
+			}
+		}()
+	}
+
+	if vGeneral.RateProfile != "" {
+		runSchedule(todo_count, records)
+
+	} else {
+	feed:
+		for count := 0; count < todo_count; count++ {
+			select {
+			case records <- txnJob{count: count}:
+			case <-vStop:
+				break feed
+
+			case <-vSoakEnd:
+				break feed
+
+			}
+		}
+	}
+	close(records)
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+
+	case <-vStop:
+	}
+
+	timeout := time.Duration(vGeneral.ShutdownTimeout) * time.Second
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
+	select {
+	case <-done:
+		return true
+
+	case <-time.After(timeout):
+		grpcLog.Warningln("In-flight transactions not completed after", timeout.Seconds(), "Sec, carrying on with shutdown")
+
+	}
+
+	return false
+}
@@ -0,0 +1,112 @@
+package types
+
+import (
+	"encoding/json"
+)
+
+// FS engineResponse, as returned for paymentRT/addPayeeRT, see x/engineResponse_outbound.json and x/rep.json.
+// Everything is optional, partial entities ({entityType, entityId} only) are valid, nullable numbers are pointers.
+type TEngineResponse struct {
+	Entities         []TEntity              `json:"entities"`
+	JsonVersion      int                    `json:"jsonVersion,omitempty"`
+	OriginatingEvent map[string]interface{} `json:"originatingEvent,omitempty"`
+	OutputTime       string                 `json:"outputTime,omitempty"`
+	ProcessorId      string                 `json:"processorId,omitempty"`
+	Versions         *TVersions             `json:"versions,omitempty"`
+}
+
+type TEntity struct {
+	TenantId     string         `json:"tenantId,omitempty"`
+	EntityType   string         `json:"entityType"`
+	EntityId     string         `json:"entityId"`
+	OverallScore *TOverallScore `json:"overallScore,omitempty"`
+	Models       []TModel       `json:"models,omitempty"`
+	FailedModels []string       `json:"failedModels,omitempty"`
+	OutputTags   []TTag         `json:"outputTags,omitempty"`
+	RiskStatus   string         `json:"riskStatus,omitempty"`
+	ConfigGroups []TConfigGroup `json:"configGroups,omitempty"`
+}
+
+type TOverallScore struct {
+	AggregationModel string   `json:"aggregationModel,omitempty"`
+	OverallScore     *float64 `json:"overallScore"`
+}
+
+type TModel struct {
+	ModelId    string                 `json:"modelId"`
+	Score      *float64               `json:"score"`
+	Confidence *float64               `json:"confidence"`
+	Tags       []TTag                 `json:"tags,omitempty"`
+	ModelData  map[string]interface{} `json:"modelData,omitempty"`
+}
+
+type TTag struct {
+	Tag    string   `json:"tag"`
+	Values []string `json:"values,omitempty"`
+}
+
+// global, analytical (id) or tenant (id)
+type TConfigGroup struct {
+	Type                    string                 `json:"type"`
+	Id                      string                 `json:"id,omitempty"`
+	TriggeredRules          []string               `json:"triggeredRules,omitempty"`
+	Aggregators             []TAggregator          `json:"aggregators,omitempty"`
+	RulesContributingEvents map[string]interface{} `json:"rulesContributingEvents,omitempty"`
+}
+
+// Either a full aggregator object, or only it's id (as a plain string).
+type TAggregator struct {
+	AggregatorId   string             `json:"aggregatorId"`
+	Scores         *TAggregatorScores `json:"scores,omitempty"`
+	AggregateScore *float64           `json:"aggregateScore,omitempty"`
+	MatchedBound   *float64           `json:"matchedBound,omitempty"`
+	OutputTags     []TTag             `json:"outputTags,omitempty"`
+	SuppressedTags []TTag             `json:"suppressedTags,omitempty"`
+	Alert          bool               `json:"alert"`
+	SuppressAlert  bool               `json:"suppressAlert"`
+}
+
+func (a *TAggregator) UnmarshalJSON(data []byte) error {
+
+	var id string
+	if err := json.Unmarshal(data, &id); err == nil {
+		*a = TAggregator{AggregatorId: id}
+		return nil
+	}
+
+	// alias, else we recurse into this method
+	type aggregator TAggregator
+	return json.Unmarshal(data, (*aggregator)(a))
+}
+
+type TAggregatorScores struct {
+	Models []TModelScore `json:"models,omitempty"`
+	Tags   []TNsTag      `json:"tags,omitempty"`
+	Rules  []TRuleScore  `json:"rules,omitempty"`
+}
+
+type TModelScore struct {
+	ModelId string   `json:"modelId"`
+	Score   *float64 `json:"score"`
+}
+
+type TNsTag struct {
+	Ns  string `json:"ns"`
+	Tag string `json:"tag"`
+}
+
+type TRuleScore struct {
+	RuleId string   `json:"ruleId"`
+	Score  *float64 `json:"score"`
+}
+
+type TVersions struct {
+	ModelGraph   int                   `json:"modelGraph,omitempty"`
+	ConfigGroups []TConfigGroupVersion `json:"configGroups,omitempty"`
+}
+
+type TConfigGroupVersion struct {
+	Type    string `json:"type"`
+	Id      string `json:"id,omitempty"`
+	Version string `json:"version"`
+}
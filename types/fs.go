@@ -6,33 +6,136 @@ type Tp_general struct {
 	Hostname                string
 	Debuglevel              int
 	Echojson                int
-	Testsize                int    // Used to limit number of records posted, over rided when reading test cases from input_source,
-	Sleep                   int    // sleep time between API post
-	Httpposturl             string // FeatureSpace API URL
+	Testsize                int     // Used to limit number of records posted, over rided when reading test cases from input_source,
+	Sleep                   int     // sleep time between API post
+	Workers                 int     // number of transactions posted in parallel, each worker posts a transaction's events in order
+	RateProfile             string  // "" closed loop (sleep based), else open-loop schedule: constant, ramp, step or spike
+	TargetEPS               float64 // open-loop target events/second, each transaction counting as 2 events (RTP-* post 4), > 0 when rateProfile is set
+	RampUp                  int     // seconds, ramp from 0 to targetEPS
+	Hold                    int     // seconds, hold at targetEPS, 0 => until testsize is reached (constant/spike only)
+	RampDown                int     // seconds, ramp from targetEPS back to 0
+	Steps                   int     // step profile, number of equal steps used during ramp up/down
+	SpikeEPS                float64 // spike profile, events/second during a spike, counted as for targetEPS
+	SpikeEvery              int     // spike profile, seconds between the start of spikes
+	SpikeDuration           int     // spike profile, seconds a spike lasts
+	LagThreshold            int     // milliseconds, a transaction starting later than this after it's scheduled time is counted as behind schedule
+	Httpposturl             string  // FeatureSpace API URL
 	Call_fs_api             int
+	MaxRetries              int    // retries per event for connection errors, timeouts, 429 and 5xx, 0 => no retries
+	RetryBackoff            int    // milliseconds, base for the exponential backoff (with jitter) between retries
+	RetryMaxBackoff         int    // milliseconds, upper limit of the backoff, also caps a Retry-After header, 0 => 10000
+	HttpTimeout             int    // milliseconds, whole request incl. response body, 0 => 30000
+	DialTimeout             int    // milliseconds, TCP connect, 0 => 10000
+	TLSHandshakeTimeout     int    // milliseconds, 0 => 10000
+	ResponseHeaderTimeout   int    // milliseconds, request written -> response headers, 0 => covered by httpTimeout
+	MaxIdleConnsPerHost     int    // pooled connections per host, 0 => two per worker
+	IdleConnTimeout         int    // seconds, idle pooled connections are closed after, 0 => 90
+	KeepAlivePeriod         int    // seconds, TCP keep-alive, 0 => 30
+	DisableKeepAlives       int    // 0/1, 1 => new connection per request
+	Http2                   int    // 0/1, 1 => negotiate HTTP/2
+	GzipRequests            int    // 0/1, 1 => gzip the request body, Content-Encoding: gzip
+	ContinueOnError         int    // 0/1, 1 => a failed event is dead-lettered and the run carries on with the next transaction
+	DeadLetter_path         string // where failed events are written to, re-post using: fs_producer repost <env>
+	ShutdownTimeout         int    // seconds, on SIGINT/SIGTERM how long we wait for in-flight transactions
+	Soak                    int    // 0/1, 1 => run for soakDuration (or until stopped) instead of testsize transactions
+	SoakDuration            string // wall-clock run time, e.g. "12h" or "90m", "" => until stopped (Ctrl-C/SIGTERM)
+	ProgressInterval        int    // seconds, progress checkpoint (totals, rates, runtime stats) logged every interval, 0 => off
+	PprofAddress            string // e.g. "localhost:6060", exposes /debug/pprof and /debug/vars, "" => off
 	Cert_dir                string
 	Cert_file               string
 	Cert_key                string
-	Datamode                string  // rpp or hist
-	Sourcesystem            string  // IF hist then we can further elaborate if eft/ac/rtc
-	Json_to_file            int     // Do we output JSON to file in output_path
-	EngineResponse_to_file  int     // Do we write http response and engineResponse to file
-	Output_path             string  // output location
-	Json_from_file          int     // Do we read JSON from input_path directory and post to FS API endpoint
-	Input_path              string  // Where are my scenario JSON files located
-	MinTransactionValue     float64 // Min value if the fake transaction
-	MaxTransactionValue     float64 // Max value of the fake transaction
-	SeedFile                string  // Which seed file to read in
-	EchoSeed                int     // 0/1 Echo the seed data to terminal
-	CurrentPath             string  // current
-	OSName                  string  // OS name
-	ProxyURL_enabled        int     // Do we want to use the proxy redirect
-	ProxyURL                string  // Proxy server to use
-	Prometheus_enabled      int     // Do we want to use the prometheus instrumentation
-	Prometheus_push_gateway string  // if above = 1 then we use the <ip:port>
-	UpdateActionDates       int     // if 0 the below date and date/time is used, if = 1 then a value is generated based on current date/time of system
+	Ca_file                 string            // CA bundle (PEM) in cert_dir used to verify the server, "" => system roots
+	Pkcs12_file             string            // client identity as PKCS#12 (.p12/.pfx) in cert_dir, used instead of cert_file/cert_key
+	Pkcs12_password         string            // PKCS#12 file password
+	InsecureSkipVerify      int               // 0/1, 1 => don't verify the server certificate (self signed test endpoints only)
+	ServerName              string            // SNI/verification name override, "" => host from httpposturl
+	TLSMinVersion           string            // "1.2" (default) or "1.3"
+	TLSCipherSuites         []string          // TLS 1.2 cipher suite names, empty => Go defaults
+	CertExpiryWarnDays      int               // warn at startup when a certificate expires within, 0 => 30
+	CertExpiryFailDays      int               // refuse to start when a certificate expires within, 0 => only when expired
+	AuthType                string            // "" / none (mTLS only), bearer, apikey, hmac or oauth2
+	AuthToken               string            // bearer token
+	AuthHeader              string            // apikey header (default X-API-Key), hmac signature header (default X-Signature)
+	AuthKey                 string            // api key, or the hmac key id
+	AuthSecret              string            // hmac secret
+	OAuthTokenURL           string            // oauth2 client credentials token endpoint
+	OAuthClientId           string            // oauth2 client id
+	OAuthClientSecret       string            // oauth2 client secret
+	OAuthScope              string            // oauth2 scope, optional
+	Sink                    string            // where events go, http (default), kafka, file, stdout or memory
+	Sink_file               string            // sink file, JSON lines appended
+	KafkaBrokers            []string          // sink kafka, e.g. ["localhost:9092"]
+	KafkaTopic              string            // sink kafka, topic for all eventTypes, "" => a topic per eventType
+	KafkaTopics             map[string]string // sink kafka, eventType => topic, takes precedence over kafkaTopic
+	Cassette                string            // "" => off, record => responses recorded to cassette_file, replay => served from it
+	Cassette_file           string            // record/replay cassette, JSON lines
+	MockEngineAddress       string            // mock-engine listen address, "" => 127.0.0.1:18080
+	MockEngineTls           int               // 0/1, 1 => mock-engine serves HTTPS, client certificates required (mTLS)
+	MockEngineCert_file     string            // mock-engine server certificate, in cert_dir
+	MockEngineKey_file      string            // mock-engine server key, in cert_dir
+	MockLatencyMin          int               // milliseconds, mock-engine minimum response delay
+	MockLatencyMax          int               // milliseconds, mock-engine maximum response delay, 0 => no delay
+	MockErrorRate           float64           // 0 -> 1, fraction of requests the mock-engine fails with mockErrorStatus
+	MockErrorStatus         int               // HTTP status of injected errors, 0 => 503
+	MockRules               []Tp_mockRule     // mock-engine scoring rules, first match wins
+	Datamode                string            // rpp or hist
+	Sourcesystem            string            // IF hist then we can further elaborate if eft/ac/rtc
+	Json_to_file            int               // Do we output JSON to file in output_path
+	EngineResponse_to_file  int               // Do we write http response and engineResponse to file
+	Output_path             string            // output location
+	Runs_path               string            // per-run output directories <runs_path>/<timestamp>-<runId>/ with a manifest, used instead of output_path, "" => off
+	RunsKeep                int               // fs_producer runs <env> prune, the newest runs kept, 0 => 10
+	Results_file            string            // latency percentiles (JSON) written here at the end of the run, "" => not written
+	RuleMatrix_file         string            // rule hit matrix, <name>.csv, <name>_tenants.csv and <name>.md written at the end of the run, "" => off
+	Report_file             string            // self-contained HTML run report written at the end of the run, "" => off
+	ResultsLog_file         string            // consolidated JSONL, a line per event (request, response, outcome, timings), "" => off
+	ResultsLogGzip          int               // 0/1, 1 => resultsLog_file gzip compressed (.gz appended)
+	ResultsLogMaxSize       int               // MB, resultsLog_file rotated when it reaches, 0 => no rotation
+	DiffScoreThreshold      float64           // fs_producer diff, score deltas up to this are not a change
+	DiffMaxChanges          int               // fs_producer diff, changed events allowed before we exit 1
+	DiffLatencyThreshold    float64           // fs_producer diff, %, a larger p99 latency increase exits 1, 0 => latency not checked
+	Golden                  int               // 0/1, 1 => compare the outcomes against the golden baseline, regressions exit 1
+	Golden_file             string            // golden baseline, "" => <input_path>.golden.json
+	GoldenScoreDecimals     int               // the score is rounded to, in the baseline
+	Json_from_file          int               // Do we read JSON from input_path directory and post to FS API endpoint
+	Input_path              string            // Where are my scenario JSON files located
+	MinTransactionValue     float64           // Min value if the fake transaction
+	MaxTransactionValue     float64           // Max value of the fake transaction
+	SeedFile                string            // Which seed file to read in
+	EchoSeed                int               // 0/1 Echo the seed data to terminal
+	CurrentPath             string            // current
+	OSName                  string            // OS name
+	ProxyURL_enabled        int               // Do we want to use the proxy redirect
+	ProxyURL                string            // Proxy server to use
+	Prometheus_enabled      int               // Do we want to use the prometheus instrumentation
+	Prometheus_push_gateway string            // if above = 1 then we use the <ip:port>
+	UpdateActionDates       int               // if 0 the below date and date/time is used, if = 1 then a value is generated based on current date/time of system
 	ToBeUsedDate            string
 	ToBeUsedDateTime        string
+	SpecialBranchRate       int    // 0-100, % of generated branch id's to be drawn from the tenant's SpecialBranches instead of it's branch ranges
+	RtpAcceptDelay          int    // Milliseconds, random 0 -> N delay between the requestToPay and the debtor's response
+	RtpAcceptRate           int    // 0-100, % of requestToPay's accepted, followed by the payment pair (0 and rtpRejectRate 0 => 100)
+	RtpRejectRate           int    // 0-100, % of requestToPay's rejected, the remainder (100 - accept - reject) expire
+	RtpExpiryMinutes        int    // requestToPay expiryDateTime, minutes after the request
+	AccountSource           string // seed or sqlite, where the fake data generator sources accounts and tenants from
+	AccountDB               string // if sqlite, the database file, created via "fs_producer loaddb <env>"
+	AccountStatus           string // good, bad or any, which accounts to draw from
+	AccountTenant           string // "" => all, else only accounts for this tenantId
+	AccountType             string // "" => all, else only accounts with this AccountIDCode
+	AccountStream           int    // 0 random debtor account per transaction, 1 stream through the accounts in order as debtors
+}
+
+// Mock engine scoring rule, matches on eventType/tenantId ("" => any) and amount.value between minValue and maxValue
+// (0 => no max).
+type Tp_mockRule struct {
+	EventType      string
+	TenantId       string
+	MinValue       float64
+	MaxValue       float64
+	Score          float64  // overallScore of the ACCOUNT entity
+	TriggeredRules []string // rule id's, each as a aggregator "Rule: <id>"
+	Alert          int      // 0/1, alert raised by the triggered rules
+	RiskStatus     string   // "" => review if alert, else no-risk
 }
 
 // FS engineResponse components
@@ -163,12 +266,19 @@ type TDecoration struct {
 	Decoration []string `json:"decoration,omitempty"`
 }
 
+type TBranchRange struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
 type TTenant struct {
-	Name             string `json:"name"`
-	TenantId         string `json:"tenantid"`
-	BranchRangeStart int    `json:"branchrangestart"`
-	BranchRangeEnd   int    `json:"branchrangeend"`
-	Bicfi            string `json:"bicfi,omitempty"`
+	Name             string         `json:"name"`
+	TenantId         string         `json:"tenantid"`
+	BranchRangeStart int            `json:"branchrangestart"`
+	BranchRangeEnd   int            `json:"branchrangeend"`
+	BranchRanges     []TBranchRange `json:"branchranges,omitempty"`    // Additional ranges, duplicate tenant entries are merged into this list at seed load
+	SpecialBranches  []string       `json:"specialbranches,omitempty"` // Fixed/universal branch codes, used for universal-branch rules
+	Bicfi            string         `json:"bicfi,omitempty"`
 }
 
 type TAccount struct {